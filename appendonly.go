@@ -0,0 +1,88 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// ErrNotAppendOnly is returned by AssertAppendOnly when the server lets an
+// existing file be overwritten
+var ErrNotAppendOnly = errors.New("file is not append-only")
+
+// AssertAppendOnly checks that the server refuses to overwrite remotePath,
+// as WORM (write once, read many) servers do, by writing one byte at offset
+// 0. The server must reject the open or the write with permission denied or
+// operation unsupported; otherwise ErrNotAppendOnly is returned
+// The byte written is the file's current first byte, so a server that does
+// allow the write is left with the content unchanged. Empty files cannot be
+// checked, since writing at offset 0 would append
+func (c *Connection) AssertAppendOnly(remotePath string) (err error) {
+	defer c.observe("assertAppendOnly", remotePath, time.Now(), &err)
+	return withReplayErr(c, func(ctx context.Context) error { return c.assertAppendOnly(ctx, remotePath) })
+}
+
+func (c *Connection) assertAppendOnly(ctx context.Context, remotePath string) error {
+	if c.client() == nil {
+		return errors.New("not connected")
+	}
+
+	first, err := c.readFirstByte(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	file, err := c.openFile(ctx, remotePath, os.O_WRONLY)
+	if err != nil {
+		if isWriteRejected(err) {
+			return nil
+		}
+		return fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteAt([]byte{first}, 0); err != nil {
+		if isWriteRejected(err) {
+			return nil
+		}
+		return fmt.Errorf("write to remote file: %w", err)
+	}
+	return fmt.Errorf("%w: %s accepted a write at offset 0", ErrNotAppendOnly, remotePath)
+}
+
+// readFirstByte returns the first byte of a remote file
+func (c *Connection) readFirstByte(ctx context.Context, remotePath string) (byte, error) {
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return 0, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	var buf [1]byte
+	if _, err := io.ReadFull(file, buf[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("%s is empty, append-only mode cannot be checked", remotePath)
+		}
+		return 0, fmt.Errorf("read remote file: %w", err)
+	}
+	return buf[0], nil
+}
+
+// isWriteRejected reports whether err is the server refusing a write with
+// SSH_FX_PERMISSION_DENIED or SSH_FX_OP_UNSUPPORTED
+func isWriteRejected(err error) bool {
+	if errors.Is(err, os.ErrPermission) {
+		return true
+	}
+	var statusErr *sftp.StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	code := statusErr.FxCode()
+	return code == sftp.ErrSSHFxPermissionDenied || code == sftp.ErrSSHFxOpUnsupported
+}
@@ -0,0 +1,58 @@
+package sftp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// TestConnection_AssertAppendOnly verifies rejected overwrites pass and
+// accepted ones are reported without changing the file
+func TestConnection_AssertAppendOnly(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/worm/audit.log", []byte("entry 1\n"))
+	server.WriteFile(t, "/worm/unsupported.log", []byte("entry 1\n"))
+	server.WriteFile(t, "/plain.log", []byte("entry 1\n"))
+	server.WriteFile(t, "/empty.log", nil)
+	server.RejectWrites("/worm/audit.log", sftp.ErrSSHFxPermissionDenied)
+	server.RejectWrites("/worm/unsupported.log", sftp.ErrSSHFxOpUnsupported)
+
+	t.Run("Permission denied passes", func(t *testing.T) {
+		if err := conn.AssertAppendOnly("/worm/audit.log"); err != nil {
+			t.Errorf("expected append-only file to pass, got: %v", err)
+		}
+	})
+
+	t.Run("Operation unsupported passes", func(t *testing.T) {
+		if err := conn.AssertAppendOnly("/worm/unsupported.log"); err != nil {
+			t.Errorf("expected append-only file to pass, got: %v", err)
+		}
+	})
+
+	t.Run("Writable file returns ErrNotAppendOnly", func(t *testing.T) {
+		if err := conn.AssertAppendOnly("/plain.log"); !errors.Is(err, ErrNotAppendOnly) {
+			t.Errorf("expected ErrNotAppendOnly, got: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/plain.log")); got != "entry 1\n" {
+			t.Errorf("expected content unchanged, got %q", got)
+		}
+	})
+
+	t.Run("Empty or missing file returns error", func(t *testing.T) {
+		if err := conn.AssertAppendOnly("/empty.log"); err == nil || errors.Is(err, ErrNotAppendOnly) {
+			t.Errorf("expected error for an empty file, got: %v", err)
+		}
+		if err := conn.AssertAppendOnly("/missing.log"); err == nil {
+			t.Error("expected error for a missing file")
+		}
+	})
+
+	t.Run("AssertAppendOnly returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).AssertAppendOnly("/plain.log")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package sftp
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime falls back to the modification time on platforms where the
+// access time is not exposed through os.FileInfo
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
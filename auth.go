@@ -0,0 +1,64 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTPAuthProvider supplies SSH authentication methods at connect time, so
+// short-lived credentials (e.g. Vault dynamic SSH keys) can be fetched per
+// connection without changes to the extension
+type SFTPAuthProvider interface {
+	AuthMethods() ([]ssh.AuthMethod, error)
+}
+
+// StaticPasswordProvider authenticates with a fixed password
+type StaticPasswordProvider struct {
+	Password string
+}
+
+// AuthMethods returns password authentication
+func (p StaticPasswordProvider) AuthMethods() ([]ssh.AuthMethod, error) {
+	return []ssh.AuthMethod{ssh.Password(p.Password)}, nil
+}
+
+// StaticKeyProvider authenticates with a fixed PEM encoded private key,
+// decrypted with Passphrase if it is encrypted
+type StaticKeyProvider struct {
+	PrivateKey string
+	Passphrase string
+}
+
+// AuthMethods returns public key authentication
+func (p StaticKeyProvider) AuthMethods() ([]ssh.AuthMethod, error) {
+	if p.PrivateKey == "" {
+		return nil, errors.New("no private key")
+	}
+	return ConnectionOptions{PrivateKey: p.PrivateKey, Passphrase: p.Passphrase}.authMethods()
+}
+
+// ConnectWithProvider connects using the authentication methods returned by
+// provider, called once per connection attempt
+// Returns a Connection that the caller owns and must close
+func (c *Client) ConnectWithProvider(host, username string, port int, provider SFTPAuthProvider) (*Connection, error) {
+	if provider == nil {
+		return nil, errors.New("no auth provider")
+	}
+
+	methods, err := provider.AuthMethods()
+	if err != nil {
+		return nil, fmt.Errorf("auth provider: %w", err)
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("auth provider returned no auth methods")
+	}
+
+	return c.ConnectWithOptions(NewConnectionOptions(
+		WithHost(host),
+		WithPort(port),
+		WithUsername(username),
+		WithAuthMethods(methods...),
+	))
+}
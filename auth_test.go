@@ -0,0 +1,70 @@
+package sftp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// failingProvider is an SFTPAuthProvider whose credential lookup fails
+type failingProvider struct{}
+
+func (failingProvider) AuthMethods() ([]ssh.AuthMethod, error) {
+	return nil, errors.New("vault unavailable")
+}
+
+// TestClient_ConnectWithProvider verifies connections authenticate with the
+// methods supplied by an SFTPAuthProvider
+func TestClient_ConnectWithProvider(t *testing.T) {
+	server := NewMockServer(t)
+	c := &Client{}
+
+	t.Run("Static password provider", func(t *testing.T) {
+		conn, err := c.ConnectWithProvider(server.Host, server.User, server.Port,
+			StaticPasswordProvider{Password: server.Password})
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("Static key provider", func(t *testing.T) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+		block, err := ssh.MarshalPrivateKey(key, "")
+		if err != nil {
+			t.Fatalf("marshal key: %v", err)
+		}
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			t.Fatalf("create signer: %v", err)
+		}
+		server.AuthorizedKey = signer.PublicKey()
+
+		conn, err := c.ConnectWithProvider(server.Host, server.User, server.Port,
+			StaticKeyProvider{PrivateKey: string(pem.EncodeToMemory(block))})
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("Provider error is returned", func(t *testing.T) {
+		_, err := c.ConnectWithProvider(server.Host, server.User, server.Port, failingProvider{})
+		if err == nil || err.Error() != "auth provider: vault unavailable" {
+			t.Errorf("expected provider error, got: %v", err)
+		}
+	})
+
+	t.Run("Nil provider returns error", func(t *testing.T) {
+		if _, err := c.ConnectWithProvider(server.Host, server.User, server.Port, nil); err == nil {
+			t.Error("expected error for nil provider, got nil")
+		}
+	})
+}
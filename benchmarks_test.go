@@ -0,0 +1,103 @@
+package sftp
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+)
+
+const (
+	// benchFiles and benchFileSize set the workload of the upload
+	// benchmarks: 100 files of 1 MiB each
+	benchFiles    = 100
+	benchFileSize = 1 << 20
+	// benchConnections is the number of connections BenchmarkUpload_Parallel
+	// fans the files out over
+	benchConnections = 10
+	// benchRoot is the remote directory the upload benchmarks write below
+	benchRoot = "/upload/xk6-sftp-bench"
+)
+
+// benchConnect connects to the SFTP server named by the SFTP_TEST_*
+// variables, skipping the benchmark if SFTP_TEST_HOST is not set, and
+// changes into a fresh directory below benchRoot removed at cleanup
+func benchConnect(b *testing.B, dir string) *Connection {
+	b.Helper()
+
+	host := os.Getenv("SFTP_TEST_HOST")
+	if host == "" {
+		b.Skip("Skipping benchmark: SFTP_TEST_HOST not set")
+	}
+	port := 22
+	if value := os.Getenv("SFTP_TEST_PORT"); value != "" {
+		var err error
+		if port, err = strconv.Atoi(value); err != nil {
+			b.Fatalf("invalid SFTP_TEST_PORT %q: %v", value, err)
+		}
+	}
+
+	conn, err := (&Client{}).Connect(host, os.Getenv("SFTP_TEST_USER"), os.Getenv("SFTP_TEST_PASS"), port)
+	if err != nil {
+		b.Fatalf("connect failed: %v", err)
+	}
+
+	remoteDir := benchRoot + "/" + dir
+	if err := conn.client().MkdirAll(remoteDir); err != nil {
+		conn.Close()
+		b.Fatalf("mkdir %s: %v", remoteDir, err)
+	}
+	if err := conn.Chdir(remoteDir); err != nil {
+		conn.Close()
+		b.Fatalf("chdir %s: %v", remoteDir, err)
+	}
+
+	b.Cleanup(func() {
+		conn.RemoveAll(remoteDir)
+		conn.Close()
+	})
+	return conn
+}
+
+// BenchmarkUpload_Sequential uploads benchFiles files one after the other
+// over a single connection
+func BenchmarkUpload_Sequential(b *testing.B) {
+	conn := benchConnect(b, "sequential")
+	payload := bytes.Repeat([]byte("x"), benchFileSize)
+
+	b.ReportAllocs()
+	b.SetBytes(benchFiles * benchFileSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for n := 0; n < benchFiles; n++ {
+			if err := conn.Upload(payload, fmt.Sprintf("file-%03d.bin", n)); err != nil {
+				b.Fatalf("upload %d failed: %v", n, err)
+			}
+		}
+	}
+}
+
+// BenchmarkUpload_Parallel uploads the same benchFiles files with
+// UploadFanOut over benchConnections connections. Each connection works in
+// its own directory, so every round of the fan-out writes distinct files
+func BenchmarkUpload_Parallel(b *testing.B) {
+	connections := make([]*Connection, benchConnections)
+	for i := range connections {
+		connections[i] = benchConnect(b, fmt.Sprintf("parallel-%02d", i))
+	}
+	payload := bytes.Repeat([]byte("x"), benchFileSize)
+
+	b.ReportAllocs()
+	b.SetBytes(benchFiles * benchFileSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for round := 0; round < benchFiles/benchConnections; round++ {
+			for n, err := range UploadFanOut(payload, fmt.Sprintf("file-%03d.bin", round), connections) {
+				if err != nil {
+					b.Fatalf("upload on connection %d failed: %v", n, err)
+				}
+			}
+		}
+	}
+}
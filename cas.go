@@ -0,0 +1,118 @@
+package sftp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// CASIndex is a content-addressed index stored as a JSON file on the
+// server, mapping the SHA-256 digest of uploaded content to its remote path
+// It lets test runs skip uploading data that is already on the server
+// without downloading it to compare. Updates are serialised per CASIndex but
+// not across processes: concurrent writers from several k6 instances can
+// lose each other's entries
+type CASIndex struct {
+	conn      *Connection
+	indexPath string
+	mu        sync.Mutex
+}
+
+// OpenCASIndex returns the CASIndex stored at indexPath, e.g.
+// /cas/index.json; the file is created by the first UploadCAS
+func (c *Connection) OpenCASIndex(indexPath string) *CASIndex {
+	return &CASIndex{conn: c, indexPath: indexPath}
+}
+
+// UploadCAS uploads srcbytes to dstPath and records its digest in the
+// index. If the digest is already indexed the upload is skipped and the
+// path it was stored at is returned instead of dstPath
+func (x *CASIndex) UploadCAS(srcbytes []byte, dstPath string) (remotePath string, err error) {
+	defer x.conn.observe("uploadCAS", dstPath, time.Now(), &err)
+
+	if x.conn.client() == nil {
+		return "", errors.New("not connected")
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return withReplay(x.conn, func(ctx context.Context) (string, error) { return x.uploadCAS(ctx, srcbytes, dstPath) })
+}
+
+func (x *CASIndex) uploadCAS(ctx context.Context, srcbytes []byte, dstPath string) (string, error) {
+	index, err := x.load(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(srcbytes)
+	digest := hex.EncodeToString(sum[:])
+	if existing, ok := index[digest]; ok {
+		return existing, nil
+	}
+
+	if err := x.conn.upload(ctx, srcbytes, dstPath); err != nil {
+		return "", err
+	}
+
+	index[digest] = dstPath
+	if err := x.store(ctx, index); err != nil {
+		return "", err
+	}
+	return dstPath, nil
+}
+
+// LookupCAS returns the remote path recorded for a hex encoded SHA-256
+// digest; ok is false if the digest is not indexed or the index cannot be
+// read
+func (x *CASIndex) LookupCAS(digest string) (remotePath string, ok bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	index, err := withReplay(x.conn, x.load)
+	if err != nil {
+		x.conn.reportError("lookupCAS", x.indexPath, err)
+		return "", false
+	}
+	remotePath, ok = index[digest]
+	return remotePath, ok
+}
+
+// load reads the index, treating a missing index file as empty
+func (x *CASIndex) load(ctx context.Context) (map[string]string, error) {
+	data, err := x.conn.readAll(ctx, x.indexPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read cas index: %w", err)
+	}
+
+	index := map[string]string{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parse cas index %s: %w", x.indexPath, err)
+	}
+	return index, nil
+}
+
+// store writes the index back, creating its directory if needed
+func (x *CASIndex) store(ctx context.Context, index map[string]string) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal cas index: %w", err)
+	}
+	if err := x.conn.client().MkdirAll(x.conn.resolvePath(path.Dir(x.indexPath))); err != nil {
+		return fmt.Errorf("create cas index directory: %w", err)
+	}
+	if err := x.conn.upload(ctx, data, x.indexPath); err != nil {
+		return fmt.Errorf("write cas index: %w", err)
+	}
+	return nil
+}
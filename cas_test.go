@@ -0,0 +1,57 @@
+package sftp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// TestCASIndex verifies uploads are recorded by digest and identical
+// content is not uploaded twice
+func TestCASIndex(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	index := conn.OpenCASIndex("/cas/index.json")
+
+	sum := sha256.Sum256([]byte("fixture"))
+	digest := hex.EncodeToString(sum[:])
+
+	t.Run("Unknown digest is not found", func(t *testing.T) {
+		if _, ok := index.LookupCAS(digest); ok {
+			t.Error("expected digest to be missing from an empty index")
+		}
+	})
+
+	t.Run("Upload records the digest", func(t *testing.T) {
+		remotePath, err := index.UploadCAS([]byte("fixture"), "/fixture-1.bin")
+		if err != nil {
+			t.Fatalf("UploadCAS failed: %v", err)
+		}
+		if remotePath != "/fixture-1.bin" {
+			t.Errorf("expected /fixture-1.bin, got %q", remotePath)
+		}
+		if got, ok := index.LookupCAS(digest); !ok || got != "/fixture-1.bin" {
+			t.Errorf("expected digest to map to /fixture-1.bin, got %q (ok=%v)", got, ok)
+		}
+	})
+
+	t.Run("Identical content is deduplicated", func(t *testing.T) {
+		remotePath, err := conn.OpenCASIndex("/cas/index.json").UploadCAS([]byte("fixture"), "/fixture-2.bin")
+		if err != nil {
+			t.Fatalf("UploadCAS failed: %v", err)
+		}
+		if remotePath != "/fixture-1.bin" {
+			t.Errorf("expected existing path /fixture-1.bin, got %q", remotePath)
+		}
+		if _, err := conn.Stat("/fixture-2.bin"); err == nil {
+			t.Error("expected duplicate content not to be uploaded")
+		}
+	})
+
+	t.Run("UploadCAS returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).OpenCASIndex("/cas/index.json").UploadCAS([]byte("data"), "/data.bin")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package sftp
+
+import (
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ConnectionID returns the random UUID identifying the connection in log
+// lines and metric tags as conn_id, e.g. to correlate a k6 iteration with
+// server or application logs
+// The ID is assigned when the connection is established and replaced
+// whenever its SFTP session is reopened
+func (c *Connection) ConnectionID() string {
+	if id := c.id.Load(); id != nil {
+		return *id
+	}
+	return ""
+}
+
+// assignConnectionID gives the connection a new ID and returns it
+func (c *Connection) assignConnectionID() string {
+	id := uuid.NewString()
+	c.id.Store(&id)
+	return id
+}
+
+// logger returns the VU's logger with the conn_id field, plus label if the
+// connection has one, or nil outside of the VU context
+func (c *Connection) logger() logrus.FieldLogger {
+	state := c.vuState()
+	if state == nil || state.Logger == nil {
+		return nil
+	}
+	logger := state.Logger.WithField("conn_id", c.ConnectionID())
+	if label := c.Label(); label != "" {
+		logger = logger.WithField("label", label)
+	}
+	return logger
+}
@@ -0,0 +1,50 @@
+package sftp
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// TestConnection_ConnectionID verifies every connection and session gets
+// its own stable UUID
+func TestConnection_ConnectionID(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("ID is a UUID v4", func(t *testing.T) {
+		id, err := uuid.Parse(conn.ConnectionID())
+		if err != nil {
+			t.Fatalf("expected a UUID, got %q: %v", conn.ConnectionID(), err)
+		}
+		if id.Version() != 4 {
+			t.Errorf("expected version 4, got %d", id.Version())
+		}
+	})
+
+	t.Run("ID is stable", func(t *testing.T) {
+		if first, second := conn.ConnectionID(), conn.ConnectionID(); first != second {
+			t.Errorf("expected the same ID twice, got %s and %s", first, second)
+		}
+	})
+
+	t.Run("Connections and sessions get distinct IDs", func(t *testing.T) {
+		other := server.Connect(t)
+		session, err := conn.openSession()
+		if err != nil {
+			t.Fatalf("openSession failed: %v", err)
+		}
+		defer session.Close()
+
+		ids := map[string]bool{conn.ConnectionID(): true, other.ConnectionID(): true, session.ConnectionID(): true}
+		if len(ids) != 3 {
+			t.Errorf("expected 3 distinct IDs, got %v", ids)
+		}
+	})
+
+	t.Run("Unconnected connection has no ID", func(t *testing.T) {
+		if id := (&Connection{}).ConnectionID(); id != "" {
+			t.Errorf("expected empty ID, got %q", id)
+		}
+	})
+}
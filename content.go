@@ -0,0 +1,326 @@
+package sftp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// encodingSniffLen is how much of a file DetectEncoding inspects
+const encodingSniffLen = 8 * 1024
+
+// CountLines streams a remote file and returns its number of lines,
+// counting a final line without a trailing newline as well
+// Lines of any length are supported and the file is never held in memory
+func (c *Connection) CountLines(remotePath string) (_ int64, err error) {
+	defer c.observe("countLines", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (int64, error) { return c.countLines(ctx, remotePath) })
+}
+
+func (c *Connection) countLines(ctx context.Context, remotePath string) (int64, error) {
+	if c.client() == nil {
+		return 0, errors.New("not connected")
+	}
+
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return 0, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	var lines int64
+	last := byte('\n')
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+			last = buf[n-1]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read remote file: %w", err)
+		}
+	}
+
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
+
+// Grep streams a remote file and returns the lines matching the regular
+// expression pattern, without their line endings
+// Stops reading after ConnectionOptions.MaxGrepResults matches
+func (c *Connection) Grep(remotePath, pattern string) (_ []string, err error) {
+	defer c.observe("grep", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) ([]string, error) { return c.grep(ctx, remotePath, pattern) })
+}
+
+func (c *Connection) grep(ctx context.Context, remotePath, pattern string) ([]string, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	limit := c.opts.MaxGrepResults
+	if limit <= 0 {
+		limit = defaultMaxGrepResults
+	}
+
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	matches := []string{}
+	r := bufio.NewReaderSize(file, 64*1024)
+	for len(matches) < limit {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte{'\n'}), []byte{'\r'})
+			if re.Match(line) {
+				matches = append(matches, string(line))
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read remote file: %w", err)
+		}
+	}
+
+	return matches, nil
+}
+
+// findPatternBlock is how much of a file FindPattern reads at a time
+const findPatternBlock = 64 * 1024
+
+// FindPattern streams a remote file and returns the byte offset of the
+// first occurrence of pattern, or -1 if it does not occur
+// The file is read in blocks that overlap by len(pattern)-1 bytes, so a
+// match across a block boundary is found without holding the file in
+// memory
+func (c *Connection) FindPattern(remotePath string, pattern []byte) (_ int64, err error) {
+	defer c.observe("findPattern", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (int64, error) { return c.findPattern(ctx, remotePath, pattern) })
+}
+
+func (c *Connection) findPattern(ctx context.Context, remotePath string, pattern []byte) (int64, error) {
+	if c.client() == nil {
+		return -1, errors.New("not connected")
+	}
+	if len(pattern) == 0 {
+		return -1, errors.New("empty pattern")
+	}
+
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return -1, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	overlap := len(pattern) - 1
+	buf := make([]byte, overlap+findPatternBlock)
+	var offset int64 // file offset of buf[0]
+	kept := 0
+	for {
+		n, err := file.Read(buf[kept:])
+		window := buf[:kept+n]
+		if i := bytes.Index(window, pattern); i >= 0 {
+			return offset + int64(i), nil
+		}
+		if errors.Is(err, io.EOF) {
+			return -1, nil
+		}
+		if err != nil {
+			return -1, fmt.Errorf("read remote file: %w", err)
+		}
+
+		// Keep the tail a match crossing into the next block starts in
+		kept = min(overlap, len(window))
+		copy(buf, window[len(window)-kept:])
+		offset += int64(len(window) - kept)
+	}
+}
+
+// ByteHistogram streams a remote file and counts how often each byte value
+// occurs, indexed by value, e.g. to check that a compressed or encrypted
+// file is close to uniformly distributed
+func (c *Connection) ByteHistogram(remotePath string) (_ [256]uint64, err error) {
+	defer c.observe("byteHistogram", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) ([256]uint64, error) { return c.byteHistogram(ctx, remotePath) })
+}
+
+func (c *Connection) byteHistogram(ctx context.Context, remotePath string) ([256]uint64, error) {
+	var histogram [256]uint64
+	if c.client() == nil {
+		return histogram, errors.New("not connected")
+	}
+
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return histogram, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := file.Read(buf)
+		for _, b := range buf[:n] {
+			histogram[b]++
+		}
+		if errors.Is(err, io.EOF) {
+			return histogram, nil
+		}
+		if err != nil {
+			return [256]uint64{}, fmt.Errorf("read remote file: %w", err)
+		}
+	}
+}
+
+// ErrEncodingMismatch is returned by AssertEncoding when a file is not in
+// the expected encoding
+var ErrEncodingMismatch = errors.New("encoding mismatch")
+
+// DetectEncoding sniffs the character encoding of a remote file from its
+// first 8 KiB and returns its WHATWG name, e.g. "utf-8", "utf-16le" or
+// "windows-1252". A byte order mark wins; otherwise valid UTF-8 is reported
+// as "utf-8" and anything else as "windows-1252"
+func (c *Connection) DetectEncoding(remotePath string) (_ string, err error) {
+	defer c.observe("detectEncoding", remotePath, time.Now(), &err)
+
+	prefix, truncated, err := c.readPrefix(remotePath, encodingSniffLen)
+	if err != nil {
+		return "", err
+	}
+	return encodingName(sniffEncoding(prefix, truncated))
+}
+
+// AssertEncoding returns ErrEncodingMismatch unless DetectEncoding finds
+// remotePath in expectedEncoding, given as any WHATWG label, e.g. "utf8" or
+// "latin1" (an alias of windows-1252)
+// Plain ASCII without a byte order mark is valid in both utf-8 and
+// windows-1252 and matches either
+func (c *Connection) AssertEncoding(remotePath, expectedEncoding string) (err error) {
+	defer c.observe("assertEncoding", remotePath, time.Now(), &err)
+
+	expected, err := encodingName(expectedEncoding)
+	if err != nil {
+		return err
+	}
+
+	prefix, truncated, err := c.readPrefix(remotePath, encodingSniffLen)
+	if err != nil {
+		return err
+	}
+	detected, err := encodingName(sniffEncoding(prefix, truncated))
+	if err != nil {
+		return err
+	}
+
+	if detected == expected {
+		return nil
+	}
+	if detected == "utf-8" && expected == "windows-1252" && isASCII(prefix) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is %s, expected %s", ErrEncodingMismatch, remotePath, detected, expected)
+}
+
+// readPrefix reads up to n bytes from the start of remotePath, reporting
+// whether the file may continue beyond them
+func (c *Connection) readPrefix(remotePath string, n int) ([]byte, bool, error) {
+	if c.client() == nil {
+		return nil, false, errors.New("not connected")
+	}
+
+	prefix, err := withReplay(c, func(ctx context.Context) ([]byte, error) {
+		file, err := c.open(ctx, remotePath)
+		if err != nil {
+			return nil, fmt.Errorf("open remote file: %w", err)
+		}
+		defer file.Close()
+
+		buf := make([]byte, n)
+		read, err := io.ReadFull(file, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("read remote file: %w", err)
+		}
+		return buf[:read], nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return prefix, len(prefix) == n, nil
+}
+
+// encodingName returns the canonical WHATWG name for an encoding label
+func encodingName(label string) (string, error) {
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return "", fmt.Errorf("unknown encoding %q: %w", label, err)
+	}
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		return "", fmt.Errorf("unknown encoding %q: %w", label, err)
+	}
+	return name, nil
+}
+
+// isASCII reports whether b holds only 7-bit bytes
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// sniffEncoding returns the encoding label for a text prefix: the byte
+// order mark if there is one, else utf-8 if the bytes are valid UTF-8 and
+// windows-1252, the WHATWG default for legacy text, otherwise
+// truncated reports that the prefix may end in the middle of a character
+func sniffEncoding(prefix []byte, truncated bool) string {
+	switch {
+	case bytes.HasPrefix(prefix, []byte{0xef, 0xbb, 0xbf}):
+		return "utf-8"
+	case bytes.HasPrefix(prefix, []byte{0xff, 0xfe}):
+		return "utf-16le"
+	case bytes.HasPrefix(prefix, []byte{0xfe, 0xff}):
+		return "utf-16be"
+	}
+
+	if truncated {
+		// Drop a multi-byte character cut off at the end of the prefix
+		for i := 1; i < utf8.UTFMax && i <= len(prefix); i++ {
+			if utf8.RuneStart(prefix[len(prefix)-i]) {
+				if !utf8.FullRune(prefix[len(prefix)-i:]) {
+					prefix = prefix[:len(prefix)-i]
+				}
+				break
+			}
+		}
+	}
+	if utf8.Valid(prefix) {
+		return "utf-8"
+	}
+	return "windows-1252"
+}
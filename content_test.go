@@ -0,0 +1,346 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestConnection_CountLines verifies lines are counted like a line scanner
+func TestConnection_CountLines(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	tests := []struct {
+		name    string
+		content string
+		want    int64
+	}{
+		{"Empty file", "", 0},
+		{"Trailing newline", "a\nb\nc\n", 3},
+		{"No trailing newline", "a\nb\nc", 3},
+		{"Blank lines", "\n\n\n", 3},
+		{"Line longer than a buffer", strings.Repeat("x", 200*1024) + "\nend\n", 2},
+		{"Many lines", strings.Repeat("log line\n", 100000), 100000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.WriteFile(t, "/lines.log", []byte(tt.content))
+			got, err := conn.CountLines("/lines.log")
+			if err != nil {
+				t.Fatalf("CountLines failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %d lines, got %d", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Missing file returns error", func(t *testing.T) {
+		if _, err := conn.CountLines("/missing.log"); err == nil {
+			t.Error("expected error for missing file, got nil")
+		}
+	})
+
+	t.Run("CountLines returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).CountLines("/lines.log")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_Grep verifies matching lines are returned up to the limit
+func TestConnection_Grep(t *testing.T) {
+	server := NewMockServer(t)
+	server.WriteFile(t, "/app.log", []byte("INFO start\r\nERROR disk full\nINFO ok\nERROR timeout"))
+
+	conn := server.Connect(t)
+
+	t.Run("Returns matching lines without line endings", func(t *testing.T) {
+		got, err := conn.Grep("/app.log", "^ERROR")
+		if err != nil {
+			t.Fatalf("Grep failed: %v", err)
+		}
+		want := []string{"ERROR disk full", "ERROR timeout"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("No matches returns empty slice", func(t *testing.T) {
+		got, err := conn.Grep("/app.log", "FATAL")
+		if err != nil {
+			t.Fatalf("Grep failed: %v", err)
+		}
+		if got == nil || len(got) != 0 {
+			t.Errorf("expected empty slice, got %#v", got)
+		}
+	})
+
+	t.Run("Invalid pattern returns error", func(t *testing.T) {
+		if _, err := conn.Grep("/app.log", "("); err == nil {
+			t.Error("expected error for invalid pattern, got nil")
+		}
+	})
+
+	t.Run("Results are capped at MaxGrepResults", func(t *testing.T) {
+		server.WriteFile(t, "/big.log", []byte(strings.Repeat("match\n", 50)))
+
+		opts := server.Options()
+		opts.MaxGrepResults = 10
+		limited, err := (&Client{}).ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer limited.Close()
+
+		got, err := limited.Grep("/big.log", "match")
+		if err != nil {
+			t.Fatalf("Grep failed: %v", err)
+		}
+		if len(got) != 10 {
+			t.Errorf("expected 10 matches, got %d", len(got))
+		}
+	})
+}
+
+// TestConnection_FindPattern verifies the first occurrence of a byte
+// sequence is found wherever it falls relative to the read blocks
+func TestConnection_FindPattern(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	magic := []byte{0x89, 'P', 'N', 'G'}
+
+	place := func(size int, offsets ...int) []byte {
+		data := bytes.Repeat([]byte{0}, size)
+		for _, off := range offsets {
+			copy(data[off:], magic)
+		}
+		return data
+	}
+
+	cases := []struct {
+		name string
+		data []byte
+		want int64
+	}{
+		{"At the start", place(100, 0), 0},
+		{"First of several", place(3*findPatternBlock, 70000, 10), 10},
+		{"In a later block", place(3*findPatternBlock, 2*findPatternBlock+5), 2*findPatternBlock + 5},
+		{"At the end", place(findPatternBlock+4, findPatternBlock), findPatternBlock},
+		{"Not found", place(2 * findPatternBlock), -1},
+		{"File shorter than pattern", []byte{0x89, 'P'}, -1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server.WriteFile(t, "/image.bin", tc.data)
+			got, err := conn.FindPattern("/image.bin", magic)
+			if err != nil {
+				t.Fatalf("FindPattern failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected offset %d, got %d", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("Across a block boundary", func(t *testing.T) {
+		// A read may end anywhere near the block size, so straddle it
+		for off := findPatternBlock - len(magic); off <= findPatternBlock+len(magic); off++ {
+			server.WriteFile(t, "/image.bin", place(3*findPatternBlock, off))
+			got, err := conn.FindPattern("/image.bin", magic)
+			if err != nil {
+				t.Fatalf("FindPattern failed: %v", err)
+			}
+			if got != int64(off) {
+				t.Errorf("expected offset %d, got %d", off, got)
+			}
+		}
+	})
+
+	t.Run("Empty pattern returns error", func(t *testing.T) {
+		if _, err := conn.FindPattern("/image.bin", nil); err == nil {
+			t.Error("expected error for an empty pattern")
+		}
+	})
+
+	t.Run("Missing file returns error", func(t *testing.T) {
+		if _, err := conn.FindPattern("/missing.bin", magic); err == nil {
+			t.Error("expected error for a missing file")
+		}
+	})
+
+	t.Run("FindPattern returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).FindPattern("/image.bin", magic)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_ByteHistogram verifies every byte of a file is counted
+// under its value
+func TestConnection_ByteHistogram(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("Counts each byte value", func(t *testing.T) {
+		// Larger than one read, with every value 1000 times plus extra 'a's
+		data := make([]byte, 0, 256*1000+10)
+		for i := 0; i < 256*1000; i++ {
+			data = append(data, byte(i))
+		}
+		data = append(data, bytes.Repeat([]byte("a"), 10)...)
+		server.WriteFile(t, "/random.bin", data)
+
+		got, err := conn.ByteHistogram("/random.bin")
+		if err != nil {
+			t.Fatalf("ByteHistogram failed: %v", err)
+		}
+		for value, count := range got {
+			want := uint64(1000)
+			if value == 'a' {
+				want = 1010
+			}
+			if count != want {
+				t.Errorf("expected %d occurrences of byte %d, got %d", want, value, count)
+			}
+		}
+	})
+
+	t.Run("Empty file has an empty histogram", func(t *testing.T) {
+		server.WriteFile(t, "/empty.bin", nil)
+		got, err := conn.ByteHistogram("/empty.bin")
+		if err != nil {
+			t.Fatalf("ByteHistogram failed: %v", err)
+		}
+		if got != [256]uint64{} {
+			t.Errorf("expected all zero counts, got %v", got)
+		}
+	})
+
+	t.Run("Missing file returns error", func(t *testing.T) {
+		if _, err := conn.ByteHistogram("/missing.bin"); err == nil {
+			t.Error("expected error for a missing file")
+		}
+	})
+
+	t.Run("ByteHistogram returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).ByteHistogram("/random.bin")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_DetectEncoding verifies BOMs and UTF-8 validity are sniffed
+func TestConnection_DetectEncoding(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"ASCII", []byte("plain text"), "utf-8"},
+		{"UTF-8", []byte("Grüße, 世界"), "utf-8"},
+		{"UTF-8 BOM", []byte("\xef\xbb\xbfhello"), "utf-8"},
+		{"UTF-16LE BOM", []byte("\xff\xfeh\x00i\x00"), "utf-16le"},
+		{"UTF-16BE BOM", []byte("\xfe\xff\x00h\x00i"), "utf-16be"},
+		{"Windows-1252", []byte("Gr\xfc\xdfe"), "windows-1252"},
+		{"Empty", nil, "utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.WriteFile(t, "/text.txt", tt.content)
+			got, err := conn.DetectEncoding("/text.txt")
+			if err != nil {
+				t.Fatalf("DetectEncoding failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Character cut off at 8 KiB is still UTF-8", func(t *testing.T) {
+		content := append([]byte(strings.Repeat("a", 8*1024-1)), "é"...)
+		server.WriteFile(t, "/cut.txt", content)
+		got, err := conn.DetectEncoding("/cut.txt")
+		if err != nil {
+			t.Fatalf("DetectEncoding failed: %v", err)
+		}
+		if got != "utf-8" {
+			t.Errorf("expected utf-8, got %q", got)
+		}
+	})
+
+	t.Run("Only the first 8 KiB are inspected", func(t *testing.T) {
+		content := append([]byte(strings.Repeat("a", 8*1024)), "Gr\xfc\xdfe"...)
+		server.WriteFile(t, "/late.txt", content)
+		got, err := conn.DetectEncoding("/late.txt")
+		if err != nil {
+			t.Fatalf("DetectEncoding failed: %v", err)
+		}
+		if got != "utf-8" {
+			t.Errorf("expected utf-8 from the first 8 KiB, got %q", got)
+		}
+	})
+}
+
+// TestConnection_AssertEncoding verifies files are compared with the
+// expected encoding by canonical name
+func TestConnection_AssertEncoding(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/utf8.txt", []byte("Grüße"))
+	server.WriteFile(t, "/legacy.txt", []byte("Gr\xfc\xdfe"))
+	server.WriteFile(t, "/ascii.txt", []byte("plain"))
+
+	t.Run("Matching encoding passes", func(t *testing.T) {
+		if err := conn.AssertEncoding("/utf8.txt", "utf-8"); err != nil {
+			t.Errorf("expected utf-8 to match, got: %v", err)
+		}
+	})
+
+	t.Run("Aliases are accepted", func(t *testing.T) {
+		if err := conn.AssertEncoding("/legacy.txt", "latin1"); err != nil {
+			t.Errorf("expected latin1 to match windows-1252, got: %v", err)
+		}
+	})
+
+	t.Run("ASCII matches either encoding", func(t *testing.T) {
+		for _, enc := range []string{"utf-8", "windows-1252"} {
+			if err := conn.AssertEncoding("/ascii.txt", enc); err != nil {
+				t.Errorf("expected ASCII to match %s, got: %v", enc, err)
+			}
+		}
+	})
+
+	t.Run("Mismatch returns ErrEncodingMismatch", func(t *testing.T) {
+		err := conn.AssertEncoding("/legacy.txt", "utf-8")
+		if !errors.Is(err, ErrEncodingMismatch) || !strings.Contains(err.Error(), "windows-1252") {
+			t.Errorf("expected ErrEncodingMismatch naming windows-1252, got: %v", err)
+		}
+	})
+
+	t.Run("Unknown expected encoding returns error", func(t *testing.T) {
+		err := conn.AssertEncoding("/utf8.txt", "klingon")
+		if err == nil || errors.Is(err, ErrEncodingMismatch) {
+			t.Errorf("expected unknown encoding error, got: %v", err)
+		}
+	})
+
+	t.Run("AssertEncoding returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).AssertEncoding("/utf8.txt", "utf-8")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
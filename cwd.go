@@ -0,0 +1,84 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+)
+
+// Getwd returns the connection's working directory: the one set with
+// Chdir, or else the server's, normally the login directory
+func (c *Connection) Getwd() (_ string, err error) {
+	defer c.observe("getwd", "", time.Now(), &err)
+	return withReplay(c, func(context.Context) (string, error) { return c.getwd() })
+}
+
+func (c *Connection) getwd() (string, error) {
+	if c.client() == nil {
+		return "", errors.New("not connected")
+	}
+
+	c.cwdMu.RLock()
+	cwd := c.cwd
+	c.cwdMu.RUnlock()
+	if cwd != "" {
+		return cwd, nil
+	}
+
+	wd, err := c.client().Getwd()
+	if err != nil {
+		return "", fmt.Errorf("get working directory: %w", err)
+	}
+	return wd, nil
+}
+
+// Chdir changes the working directory relative paths are resolved
+// against, like cd in the sftp command-line client
+// SFTP has no server-side working directory, so the connection tracks it
+// and joins it onto relative paths before sending them
+func (c *Connection) Chdir(dirPath string) (err error) {
+	defer c.observe("chdir", dirPath, time.Now(), &err)
+	return withReplayErr(c, func(context.Context) error { return c.chdir(dirPath) })
+}
+
+func (c *Connection) chdir(dirPath string) error {
+	if c.client() == nil {
+		return errors.New("not connected")
+	}
+
+	dir, err := c.client().RealPath(c.resolvePath(dirPath))
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", dirPath, err)
+	}
+	info, err := c.client().Stat(dir)
+	if err != nil {
+		return fmt.Errorf("stat remote directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	c.cwdMu.Lock()
+	c.cwd = dir
+	c.cwdMu.Unlock()
+	return nil
+}
+
+// resolvePath joins a relative remotePath onto the directory set with
+// Chdir. Absolute paths, and all paths before Chdir is called, are
+// returned unchanged and resolved by the server
+func (c *Connection) resolvePath(remotePath string) string {
+	if path.IsAbs(remotePath) {
+		return remotePath
+	}
+
+	c.cwdMu.RLock()
+	cwd := c.cwd
+	c.cwdMu.RUnlock()
+	if cwd == "" {
+		return remotePath
+	}
+	return path.Join(cwd, remotePath)
+}
@@ -0,0 +1,68 @@
+package sftp
+
+import (
+	"testing"
+)
+
+// TestConnection_Chdir verifies relative paths resolve against the
+// directory set with Chdir
+func TestConnection_Chdir(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/data/in/a.txt", []byte("a"))
+
+	t.Run("Getwd defaults to the server's directory", func(t *testing.T) {
+		wd, err := conn.Getwd()
+		if err != nil || wd != "/" {
+			t.Errorf("expected /, got %q (err=%v)", wd, err)
+		}
+	})
+
+	t.Run("Relative paths follow Chdir", func(t *testing.T) {
+		if err := conn.Chdir("/data"); err != nil {
+			t.Fatalf("Chdir failed: %v", err)
+		}
+		if err := conn.Chdir("in"); err != nil {
+			t.Fatalf("relative Chdir failed: %v", err)
+		}
+		if wd, _ := conn.Getwd(); wd != "/data/in" {
+			t.Errorf("expected /data/in, got %q", wd)
+		}
+
+		if err := conn.Upload([]byte("b"), "b.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/data/in/b.txt")); got != "b" {
+			t.Errorf("expected b.txt in /data/in, got %q", got)
+		}
+		result, err := conn.Ls(".")
+		if err != nil || result.Total != 2 {
+			t.Errorf("expected 2 entries in /data/in, got %v (err=%v)", result, err)
+		}
+		if _, err := conn.Stat("../in/a.txt"); err != nil {
+			t.Errorf("expected ../in/a.txt to resolve, got: %v", err)
+		}
+		if _, err := conn.Stat("/data/in/a.txt"); err != nil {
+			t.Errorf("expected absolute paths to be unaffected, got: %v", err)
+		}
+	})
+
+	t.Run("Chdir to a file or missing path fails", func(t *testing.T) {
+		if err := conn.Chdir("a.txt"); err == nil {
+			t.Error("expected error changing into a file")
+		}
+		if err := conn.Chdir("/missing"); err == nil {
+			t.Error("expected error changing into a missing directory")
+		}
+		if wd, _ := conn.Getwd(); wd != "/data/in" {
+			t.Errorf("expected working directory unchanged, got %q", wd)
+		}
+	})
+
+	t.Run("Chdir returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).Chdir("/data")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package sftp
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// logOperation writes the debug log line for a finished operation to the
+// VU's logger. The fields are structured, so k6 run --log-format=json
+// prints one JSON object per operation; error is null on success
+// Nothing is logged outside of the VU context
+func (c *Connection) logOperation(op, remotePath, localPath string, n int64, elapsed time.Duration, err error) {
+	logger := c.logger()
+	if logger == nil {
+		return
+	}
+
+	var errField interface{}
+	if err != nil {
+		errField = err.Error()
+	}
+	logger.WithFields(logrus.Fields{
+		"timestamp":         time.Now().Format(time.RFC3339Nano),
+		"op":                op,
+		"remote_path":       remotePath,
+		"local_path":        localPath,
+		"bytes_transferred": n,
+		"duration_ms":       float64(elapsed) / float64(time.Millisecond),
+		"error":             errField,
+	}).Info("sftp operation")
+}
+
+// logConnect writes the debug log line for a finished connection attempt,
+// naming the server by opts.RedactedString so no credential is logged
+// Deferred with named results like observe; nothing is logged without
+// Debug or outside of the VU context
+func (c *Client) logConnect(opts ConnectionOptions, start time.Time, conn **Connection, err *error) {
+	if !opts.Debug || c.vu == nil {
+		return
+	}
+	state := c.vu.State()
+	if state == nil || state.Logger == nil {
+		return
+	}
+
+	var logger logrus.FieldLogger = state.Logger
+	if *conn != nil {
+		logger = (*conn).logger()
+	}
+	var errField interface{}
+	if *err != nil {
+		errField = (*err).Error()
+	}
+	logger.WithFields(logrus.Fields{
+		"timestamp":   time.Now().Format(time.RFC3339Nano),
+		"op":          "connect",
+		"options":     opts.RedactedString(),
+		"duration_ms": float64(time.Since(start)) / float64(time.Millisecond),
+		"error":       errField,
+	}).Info("sftp connect")
+}
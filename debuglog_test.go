@@ -0,0 +1,198 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"go.k6.io/k6/js/modulestest"
+	"go.k6.io/k6/lib"
+)
+
+// TestConnection_DebugLog verifies Debug logs one structured line per
+// operation with its transfer details
+func TestConnection_DebugLog(t *testing.T) {
+	server := NewMockServer(t)
+	logger, hook := logtest.NewNullLogger()
+	client := &Client{vu: &modulestest.VU{
+		CtxField:   context.Background(),
+		StateField: &lib.State{Logger: logger},
+	}}
+
+	connect := func(t *testing.T, debug bool) *Connection {
+		t.Helper()
+		opts := server.Options()
+		opts.Debug = debug
+		opts.Label = "worker-0"
+		conn, err := client.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	lastEntry := func(t *testing.T) logrus.Fields {
+		t.Helper()
+		entry := hook.LastEntry()
+		if entry == nil {
+			t.Fatal("expected a log entry")
+		}
+		if entry.Message != "sftp operation" {
+			t.Errorf("unexpected message %q", entry.Message)
+		}
+		return entry.Data
+	}
+
+	t.Run("Transfers log paths and bytes", func(t *testing.T) {
+		hook.Reset()
+		conn := connect(t, true)
+
+		if err := conn.Upload([]byte("hello"), "/debug.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		fields := lastEntry(t)
+		if fields["conn_id"] != conn.ConnectionID() || fields["label"] != "worker-0" {
+			t.Errorf("expected conn_id %s and label worker-0, got %v and %v", conn.ConnectionID(), fields["conn_id"], fields["label"])
+		}
+		if fields["op"] != "upload" || fields["remote_path"] != "/debug.txt" || fields["bytes_transferred"] != int64(5) {
+			t.Errorf("unexpected upload fields %v", fields)
+		}
+		if fields["error"] != nil {
+			t.Errorf("expected null error, got %v", fields["error"])
+		}
+		if _, ok := fields["duration_ms"].(float64); !ok {
+			t.Errorf("expected float64 duration_ms, got %T", fields["duration_ms"])
+		}
+		if _, ok := fields["timestamp"].(string); !ok {
+			t.Errorf("expected string timestamp, got %T", fields["timestamp"])
+		}
+
+		localPath := filepath.Join(t.TempDir(), "debug.txt")
+		if err := conn.Download("/debug.txt", localPath); err != nil {
+			t.Fatalf("Download failed: %v", err)
+		}
+		fields = lastEntry(t)
+		if fields["op"] != "download" || fields["local_path"] != localPath || fields["bytes_transferred"] != int64(5) {
+			t.Errorf("unexpected download fields %v", fields)
+		}
+	})
+
+	t.Run("Failures log the error", func(t *testing.T) {
+		hook.Reset()
+		conn := connect(t, true)
+
+		_, err := conn.Stat("/missing.txt")
+		if !errors.Is(err, ErrRemoteNotFound) {
+			t.Fatalf("expected ErrRemoteNotFound, got: %v", err)
+		}
+		fields := lastEntry(t)
+		if fields["op"] != "stat" || fields["error"] != err.Error() {
+			t.Errorf("unexpected stat fields %v", fields)
+		}
+	})
+
+	t.Run("Batch, stream and transaction operations are logged", func(t *testing.T) {
+		hook.Reset()
+		conn := connect(t, true)
+		if err := conn.Upload([]byte("hello"), "/batch.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+
+		ops := map[string]func(){
+			"statMany":          func() { conn.StatMany([]string{"/batch.txt"}) },
+			"downloadManyBytes": func() { conn.DownloadManyBytes([]string{"/batch.txt"}) },
+			"commit":            func() { conn.BeginBatch().Stage(UploadOp([]byte("tx"), "/tx.txt")).Commit() },
+			"downloadStream": func() {
+				data, errs := conn.DownloadStream("/batch.txt", 2)
+				for range data {
+				}
+				<-errs
+			},
+			"lsChunked": func() {
+				data, errs := conn.LsChunked("/", 1)
+				for range data {
+				}
+				<-errs
+			},
+			"lsAsync": func() {
+				data, errs := conn.LsAsync("/")
+				for range data {
+				}
+				<-errs
+			},
+		}
+		for op, run := range ops {
+			run()
+			if fields := lastEntry(t); fields["op"] != op || fields["error"] != nil {
+				t.Errorf("expected a successful %s entry, got %v", op, fields)
+			}
+		}
+	})
+
+	t.Run("Connection attempts log redacted options", func(t *testing.T) {
+		hook.Reset()
+		conn := connect(t, true)
+		entry := hook.LastEntry()
+		if entry == nil || entry.Message != "sftp connect" {
+			t.Fatalf("expected a connect entry, got %v", entry)
+		}
+		options, _ := entry.Data["options"].(string)
+		if entry.Data["conn_id"] != conn.ConnectionID() || !strings.Contains(options, "label=worker-0 password=***") {
+			t.Errorf("unexpected connect fields %v", entry.Data)
+		}
+
+		hook.Reset()
+		opts := server.Options()
+		opts.Debug = true
+		opts.Password = "wrong-secret"
+		if _, err := client.ConnectWithOptions(opts); err == nil {
+			t.Fatal("expected connect to fail")
+		}
+		entry = hook.LastEntry()
+		if entry == nil || entry.Data["error"] == nil {
+			t.Fatalf("expected a failed connect entry, got %v", entry)
+		}
+		for _, e := range hook.AllEntries() {
+			for _, v := range e.Data {
+				if s, ok := v.(string); ok && strings.Contains(s, "wrong-secret") {
+					t.Errorf("expected the password to be redacted, got %v", e.Data)
+				}
+			}
+		}
+	})
+
+	t.Run("Unlabelled connections log no label", func(t *testing.T) {
+		hook.Reset()
+		opts := server.Options()
+		opts.Debug = true
+		conn, err := client.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer conn.Close()
+
+		if err := conn.Upload([]byte("hello"), "/unlabelled.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if label, ok := lastEntry(t)["label"]; ok {
+			t.Errorf("expected no label field, got %v", label)
+		}
+	})
+
+	t.Run("Nothing is logged without Debug", func(t *testing.T) {
+		hook.Reset()
+		conn := connect(t, false)
+
+		if err := conn.Upload([]byte("quiet"), "/quiet.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if n := len(hook.AllEntries()); n != 0 {
+			t.Errorf("expected no log entries, got %d", n)
+		}
+	})
+}
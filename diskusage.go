@@ -0,0 +1,84 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// DiskUsage walks the tree rooted at rootPath and maps rootPath and every
+// directory at most maxDepth levels below it to the bytes of all regular
+// files in its subtree, like du -d maxDepth
+// Files deeper than maxDepth still count towards the directories above
+// them. Sizes are file lengths, not allocated blocks; symlinks are not
+// followed. Returns ErrRemoteNotFound if rootPath does not exist
+func (c *Connection) DiskUsage(rootPath string, maxDepth int) (_ map[string]int64, err error) {
+	defer c.observe("diskUsage", rootPath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (map[string]int64, error) { return c.diskUsage(ctx, rootPath, maxDepth) })
+}
+
+func (c *Connection) diskUsage(ctx context.Context, rootPath string, maxDepth int) (map[string]int64, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+	if maxDepth < 0 {
+		return nil, fmt.Errorf("invalid max depth %d", maxDepth)
+	}
+	if err := c.checkPathPolicy(rootPath); err != nil {
+		return nil, err
+	}
+	root := path.Clean(c.resolvePath(rootPath))
+
+	usage := map[string]int64{}
+	walker := c.client().Walk(root)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := walker.Err(); err != nil {
+			if walker.Path() == root && errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("%w: %s", ErrRemoteNotFound, rootPath)
+			}
+			return nil, fmt.Errorf("walk %s: %w", walker.Path(), err)
+		}
+
+		info := walker.Stat()
+		switch {
+		case info.IsDir():
+			// Walk lists a directory before its contents, so this never
+			// resets a total
+			if pathDepth(root, walker.Path()) <= maxDepth {
+				usage[walker.Path()] = 0
+			}
+		case info.Mode().IsRegular():
+			if walker.Path() == root {
+				usage[root] = info.Size()
+				continue
+			}
+			for dir := path.Dir(walker.Path()); ; dir = path.Dir(dir) {
+				if pathDepth(root, dir) <= maxDepth {
+					usage[dir] += info.Size()
+				}
+				if dir == root {
+					break
+				}
+			}
+		}
+	}
+
+	return usage, nil
+}
+
+// pathDepth returns how many levels p is below root, which must be p or
+// one of its parents
+func pathDepth(root, p string) int {
+	rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+	if rel == "" {
+		return 0
+	}
+	return strings.Count(rel, "/") + 1
+}
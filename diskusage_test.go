@@ -0,0 +1,81 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestConnection_DiskUsage verifies subtree totals are reported for every
+// directory down to the maximum depth
+func TestConnection_DiskUsage(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/shards/README", bytes.Repeat([]byte("r"), 5))
+	server.WriteFile(t, "/shards/a/part-0", bytes.Repeat([]byte("a"), 100))
+	server.WriteFile(t, "/shards/a/2024/part-1", bytes.Repeat([]byte("a"), 50))
+	server.WriteFile(t, "/shards/b/part-0", bytes.Repeat([]byte("b"), 120))
+	server.WriteFile(t, "/shards/b/2024/01/part-1", bytes.Repeat([]byte("b"), 30))
+	if err := conn.client().Mkdir("/shards/empty"); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	t.Run("Depth 1 totals each shard", func(t *testing.T) {
+		got, err := conn.DiskUsage("/shards", 1)
+		if err != nil {
+			t.Fatalf("DiskUsage failed: %v", err)
+		}
+		want := map[string]int64{
+			"/shards":       305,
+			"/shards/a":     150,
+			"/shards/b":     150,
+			"/shards/empty": 0,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Depth 2 includes nested directories", func(t *testing.T) {
+		got, err := conn.DiskUsage("/shards/", 2)
+		if err != nil {
+			t.Fatalf("DiskUsage failed: %v", err)
+		}
+		if got["/shards/a/2024"] != 50 || got["/shards/b/2024"] != 30 || got["/shards/b"] != 150 {
+			t.Errorf("unexpected usage %v", got)
+		}
+		if _, ok := got["/shards/b/2024/01"]; ok {
+			t.Errorf("expected nothing below depth 2, got %v", got)
+		}
+	})
+
+	t.Run("Depth 0 reports only the root", func(t *testing.T) {
+		got, err := conn.DiskUsage("/shards", 0)
+		if err != nil {
+			t.Fatalf("DiskUsage failed: %v", err)
+		}
+		if want := map[string]int64{"/shards": 305}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Missing root returns ErrRemoteNotFound", func(t *testing.T) {
+		if _, err := conn.DiskUsage("/missing", 1); !errors.Is(err, ErrRemoteNotFound) {
+			t.Errorf("expected ErrRemoteNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("Negative depth returns error", func(t *testing.T) {
+		if _, err := conn.DiskUsage("/shards", -1); err == nil {
+			t.Error("expected error for a negative depth")
+		}
+	})
+
+	t.Run("DiskUsage returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).DiskUsage("/shards", 1)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
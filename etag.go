@@ -0,0 +1,82 @@
+package sftp
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// uploadedETag is what UploadWithETag remembers about its last upload to a
+// remote path
+type uploadedETag struct {
+	etag   string
+	digest [sha256.Size]byte
+}
+
+// ETag returns a tag identifying the current version of a remote file,
+// composed of its size and modification time. It changes when the file is
+// rewritten with another size or in a later second, the resolution of
+// SFTP modification times
+// Returns ErrRemoteNotFound if the file does not exist
+func (c *Connection) ETag(remotePath string) (_ string, err error) {
+	defer c.observe("etag", remotePath, time.Now(), &err)
+	return withReplay(c, func(context.Context) (string, error) { return c.etag(remotePath) })
+}
+
+func (c *Connection) etag(remotePath string) (string, error) {
+	info, err := c.stat(remotePath)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x-%x", info["size"].(int64), info["modTime"].(int64)), nil
+}
+
+// UploadWithETag uploads srcbytes to dstPath unless this connection
+// already uploaded the same bytes there and the file's ETag shows it has
+// not changed since, e.g. for a fixture uploaded before every iteration
+// Returns the file's ETag and whether the upload was skipped. The first
+// call for a path on a connection always uploads
+func (c *Connection) UploadWithETag(srcbytes []byte, dstPath string) (etag string, skipped bool, err error) {
+	defer c.observe("uploadWithETag", dstPath, time.Now(), &err)
+
+	type result struct {
+		etag    string
+		skipped bool
+	}
+	r, err := withReplay(c, func(ctx context.Context) (result, error) {
+		etag, skipped, err := c.uploadWithETag(ctx, srcbytes, dstPath)
+		return result{etag, skipped}, err
+	})
+	return r.etag, r.skipped, err
+}
+
+func (c *Connection) uploadWithETag(ctx context.Context, srcbytes []byte, dstPath string) (etag string, skipped bool, err error) {
+	if c.client() == nil {
+		return "", false, errors.New("not connected")
+	}
+
+	key := c.resolvePath(dstPath)
+	digest := sha256.Sum256(srcbytes)
+	current, err := c.etag(dstPath)
+	switch {
+	case err == nil:
+		if last, ok := c.etags.Load(key); ok && last.(uploadedETag) == (uploadedETag{current, digest}) {
+			return current, true, nil
+		}
+	case !errors.Is(err, ErrRemoteNotFound):
+		return "", false, err
+	}
+
+	if err := c.upload(ctx, srcbytes, dstPath); err != nil {
+		c.etags.Delete(key)
+		return "", false, err
+	}
+	if etag, err = c.etag(dstPath); err != nil {
+		c.etags.Delete(key)
+		return "", false, err
+	}
+	c.etags.Store(key, uploadedETag{etag, digest})
+	return etag, false, nil
+}
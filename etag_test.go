@@ -0,0 +1,115 @@
+package sftp
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestConnection_UploadWithETag verifies uploads are skipped only for the
+// same bytes over an unchanged remote file
+func TestConnection_UploadWithETag(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	fixture := []byte("id,total\n1,100\n")
+
+	// Moves the remote file's modification time, like a rewrite in a later
+	// second would
+	touch := func(t *testing.T, remotePath string, offset time.Duration) {
+		t.Helper()
+		modTime := time.Now().Add(offset)
+		if err := os.Chtimes(server.localPath(remotePath), modTime, modTime); err != nil {
+			t.Fatalf("set file times: %v", err)
+		}
+	}
+
+	t.Run("ETag combines size and modification time", func(t *testing.T) {
+		server.WriteFile(t, "/tagged.txt", []byte("hello"))
+		modTime := time.Unix(0x65f1c2d0, 0)
+		if err := os.Chtimes(server.localPath("/tagged.txt"), modTime, modTime); err != nil {
+			t.Fatalf("set file times: %v", err)
+		}
+
+		got, err := conn.ETag("/tagged.txt")
+		if err != nil {
+			t.Fatalf("ETag failed: %v", err)
+		}
+		if got != "5-65f1c2d0" {
+			t.Errorf("expected 5-65f1c2d0, got %q", got)
+		}
+	})
+
+	t.Run("First upload is not skipped", func(t *testing.T) {
+		etag, skipped, err := conn.UploadWithETag(fixture, "/fixture.csv")
+		if err != nil {
+			t.Fatalf("UploadWithETag failed: %v", err)
+		}
+		if skipped {
+			t.Error("expected the first upload not to be skipped")
+		}
+		if current, _ := conn.ETag("/fixture.csv"); etag != current {
+			t.Errorf("expected ETag %q, got %q", current, etag)
+		}
+		if got := server.ReadFile(t, "/fixture.csv"); string(got) != string(fixture) {
+			t.Errorf("unexpected content %q", got)
+		}
+	})
+
+	t.Run("Same bytes over an unchanged file are skipped", func(t *testing.T) {
+		before, _ := conn.ETag("/fixture.csv")
+		etag, skipped, err := conn.UploadWithETag(fixture, "/fixture.csv")
+		if err != nil {
+			t.Fatalf("UploadWithETag failed: %v", err)
+		}
+		if !skipped || etag != before {
+			t.Errorf("expected skip with ETag %q, got skipped=%v etag=%q", before, skipped, etag)
+		}
+	})
+
+	t.Run("Changed remote file is uploaded again", func(t *testing.T) {
+		server.WriteFile(t, "/fixture.csv", []byte("truncated"))
+		if _, skipped, err := conn.UploadWithETag(fixture, "/fixture.csv"); err != nil || skipped {
+			t.Fatalf("expected upload, got skipped=%v err=%v", skipped, err)
+		}
+		if got := server.ReadFile(t, "/fixture.csv"); string(got) != string(fixture) {
+			t.Errorf("expected the fixture to be restored, got %q", got)
+		}
+
+		touch(t, "/fixture.csv", time.Hour)
+		if _, skipped, err := conn.UploadWithETag(fixture, "/fixture.csv"); err != nil || skipped {
+			t.Errorf("expected upload after the modification time changed, got skipped=%v err=%v", skipped, err)
+		}
+	})
+
+	t.Run("Different bytes are uploaded", func(t *testing.T) {
+		// Same size, so only the content tells them apart
+		other := []byte("id,total\n2,200\n")
+		if _, skipped, err := conn.UploadWithETag(other, "/fixture.csv"); err != nil || skipped {
+			t.Fatalf("expected upload, got skipped=%v err=%v", skipped, err)
+		}
+		if got := server.ReadFile(t, "/fixture.csv"); string(got) != string(other) {
+			t.Errorf("unexpected content %q", got)
+		}
+	})
+
+	t.Run("Other connections do not share the cache", func(t *testing.T) {
+		other := server.Connect(t)
+		if _, skipped, err := other.UploadWithETag(fixture, "/fixture.csv"); err != nil || skipped {
+			t.Errorf("expected upload on a new connection, got skipped=%v err=%v", skipped, err)
+		}
+	})
+
+	t.Run("Missing file returns ErrRemoteNotFound from ETag", func(t *testing.T) {
+		if _, err := conn.ETag("/missing.csv"); !errors.Is(err, ErrRemoteNotFound) {
+			t.Errorf("expected ErrRemoteNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("UploadWithETag returns error when not connected", func(t *testing.T) {
+		_, _, err := (&Connection{}).UploadWithETag(fixture, "/fixture.csv")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -11,10 +11,14 @@ export default function () {
     try {
         conn = sftp.connect(host, user, pass, port);
 
-        // ls() returns an array of objects with name, size, isDir, modTime properties
-        const files = conn.ls(remotePath);
+        // ls() returns { entries, total, truncated }; each entry has name,
+        // size, isDir and modTime properties
+        const { entries, total, truncated } = conn.ls(remotePath);
+        if (truncated) {
+            console.warn(`Showing ${entries.length} of ${total} entries`);
+        }
 
-        files.forEach((file) => {
+        entries.forEach((file) => {
             const type = file.isDir ? 'DIR' : 'FILE';
             console.log(`[${type}] ${file.name} (${file.size} bytes)`);
         });
@@ -0,0 +1,123 @@
+package sftp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ErrConsensusFailure is returned by DownloadConsensus when the copies of a
+// file on different connections do not match
+var ErrConsensusFailure = errors.New("replicas disagree")
+
+// UploadFanOut uploads srcbytes to dstPath on every connection in
+// parallel, one goroutine per connection, e.g. to seed N replicas at once
+// The returned slice holds each connection's error at its index, nil on
+// success; a nil connection gets a not connected error
+func UploadFanOut(srcbytes []byte, dstPath string, connections []*Connection) []error {
+	errs := make([]error, len(connections))
+
+	var wg sync.WaitGroup
+	for i, conn := range connections {
+		if conn == nil {
+			errs[i] = errors.New("not connected")
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = conn.Upload(srcbytes, dstPath)
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// DownloadConsensus downloads remotePath from every connection in parallel
+// and returns the content only if all copies have the same SHA-256
+// Returns ErrConsensusFailure listing each connection's digest if they
+// differ, or the download errors if any copy could not be read, including
+// not connected for a nil connection
+func DownloadConsensus(remotePath string, connections []*Connection) ([]byte, error) {
+	if len(connections) == 0 {
+		return nil, errors.New("no connections")
+	}
+
+	copies := make([][]byte, len(connections))
+	errs := make([]error, len(connections))
+
+	var wg sync.WaitGroup
+	for i, conn := range connections {
+		if conn == nil {
+			errs[i] = fmt.Errorf("connection %d: not connected", i)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := withReplay(conn, func(ctx context.Context) ([]byte, error) { return conn.readAll(ctx, remotePath) })
+			if err != nil {
+				errs[i] = fmt.Errorf("connection %d: %w", i, err)
+				return
+			}
+			copies[i] = data
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	digests := make([][sha256.Size]byte, len(copies))
+	agree := true
+	for i, data := range copies {
+		digests[i] = sha256.Sum256(data)
+		if digests[i] != digests[0] {
+			agree = false
+		}
+	}
+	if !agree {
+		details := make([]string, len(digests))
+		for i, digest := range digests {
+			details[i] = fmt.Sprintf("connection %d%s: sha256 %s (%d bytes)",
+				i, labelSuffix(connections[i]), hex.EncodeToString(digest[:]), len(copies[i]))
+		}
+		return nil, fmt.Errorf("%w on %s: %s", ErrConsensusFailure, remotePath, strings.Join(details, ", "))
+	}
+
+	return copies[0], nil
+}
+
+// labelSuffix formats the connection's label for error details, if it has one
+func labelSuffix(conn *Connection) string {
+	if conn.Label() == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", conn.Label())
+}
+
+// readAll reads a whole remote file into memory
+func (c *Connection) readAll(ctx context.Context, remotePath string) ([]byte, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("read remote file: %w", err)
+	}
+	return data, nil
+}
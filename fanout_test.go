@@ -0,0 +1,109 @@
+package sftp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestUploadFanOut verifies one upload reaches every connection and errors
+// are reported per connection
+func TestUploadFanOut(t *testing.T) {
+	servers := []*MockServer{NewMockServer(t), NewMockServer(t), NewMockServer(t)}
+	conns := make([]*Connection, len(servers))
+	for i, server := range servers {
+		conns[i] = server.Connect(t)
+	}
+
+	t.Run("Upload appears on all replicas", func(t *testing.T) {
+		errs := UploadFanOut([]byte("replicated"), "/replica.txt", conns)
+		if len(errs) != len(conns) {
+			t.Fatalf("expected %d errors, got %d", len(conns), len(errs))
+		}
+		for i, err := range errs {
+			if err != nil {
+				t.Errorf("replica %d: unexpected error: %v", i, err)
+			}
+			if got := string(servers[i].ReadFile(t, "/replica.txt")); got != "replicated" {
+				t.Errorf("replica %d: expected 'replicated', got %q", i, got)
+			}
+		}
+	})
+
+	t.Run("Failures are reported at the connection's index", func(t *testing.T) {
+		errs := UploadFanOut([]byte("data"), "/partial.txt", []*Connection{conns[0], {}})
+		if errs[0] != nil {
+			t.Errorf("expected success on connection 0, got: %v", errs[0])
+		}
+		if errs[1] == nil || errs[1].Error() != "not connected" {
+			t.Errorf("expected 'not connected' on connection 1, got: %v", errs[1])
+		}
+	})
+
+	t.Run("Nil connections are not connected", func(t *testing.T) {
+		errs := UploadFanOut([]byte("data"), "/nil.txt", []*Connection{nil, conns[0]})
+		if errs[0] == nil || errs[0].Error() != "not connected" {
+			t.Errorf("expected 'not connected' on connection 0, got: %v", errs[0])
+		}
+		if errs[1] != nil {
+			t.Errorf("expected success on connection 1, got: %v", errs[1])
+		}
+	})
+}
+
+// TestDownloadConsensus verifies content is returned only when all replicas agree
+func TestDownloadConsensus(t *testing.T) {
+	servers := []*MockServer{NewMockServer(t), NewMockServer(t), NewMockServer(t)}
+	conns := make([]*Connection, len(servers))
+	for i, server := range servers {
+		conns[i] = server.Connect(t)
+		server.WriteFile(t, "/agreed.txt", []byte("same"))
+	}
+
+	t.Run("Matching copies return the content", func(t *testing.T) {
+		data, err := DownloadConsensus("/agreed.txt", conns)
+		if err != nil {
+			t.Fatalf("DownloadConsensus failed: %v", err)
+		}
+		if string(data) != "same" {
+			t.Errorf("expected 'same', got %q", data)
+		}
+	})
+
+	t.Run("Differing copy returns ErrConsensusFailure", func(t *testing.T) {
+		for i, server := range servers {
+			content := "v1"
+			if i == 2 {
+				content = "v2"
+			}
+			server.WriteFile(t, "/diverged.txt", []byte(content))
+		}
+
+		data, err := DownloadConsensus("/diverged.txt", conns)
+		if !errors.Is(err, ErrConsensusFailure) {
+			t.Fatalf("expected ErrConsensusFailure, got: %v", err)
+		}
+		if data != nil {
+			t.Error("expected no content without consensus")
+		}
+		if !strings.Contains(err.Error(), "connection 2") {
+			t.Errorf("expected details naming connection 2, got: %v", err)
+		}
+	})
+
+	t.Run("Missing copy returns the download error", func(t *testing.T) {
+		servers[0].WriteFile(t, "/partial.txt", []byte("only here"))
+
+		_, err := DownloadConsensus("/partial.txt", conns)
+		if err == nil || errors.Is(err, ErrConsensusFailure) {
+			t.Errorf("expected download error, got: %v", err)
+		}
+	})
+
+	t.Run("Nil connection returns not connected", func(t *testing.T) {
+		_, err := DownloadConsensus("/agreed.txt", []*Connection{conns[0], nil})
+		if err == nil || err.Error() != "connection 1: not connected" {
+			t.Errorf("expected 'connection 1: not connected', got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SFTP open flags (SSH_FXF_*) as numbered in draft-ietf-secsh-filexfer-02,
+// section 6.3, for use with UploadWithFlags
+const (
+	// FlagRead opens the file for reading (SSH_FXF_READ)
+	FlagRead uint32 = 0x00000001
+	// FlagWrite opens the file for writing (SSH_FXF_WRITE)
+	FlagWrite uint32 = 0x00000002
+	// FlagAppend forces all writes to the end of the file (SSH_FXF_APPEND)
+	FlagAppend uint32 = 0x00000004
+	// FlagCreate creates the file if it does not exist (SSH_FXF_CREAT)
+	FlagCreate uint32 = 0x00000008
+	// FlagTrunc truncates an existing file to zero length (SSH_FXF_TRUNC)
+	FlagTrunc uint32 = 0x00000010
+	// FlagExcl fails if the file already exists; requires FlagCreate (SSH_FXF_EXCL)
+	FlagExcl uint32 = 0x00000020
+)
+
+// osFlags maps an SSH_FXF_* bitmask to the os.OpenFile flags pkg/sftp
+// translates back on the wire
+func osFlags(flags uint32) (int, error) {
+	if unknown := flags &^ (FlagRead | FlagWrite | FlagAppend | FlagCreate | FlagTrunc | FlagExcl); unknown != 0 {
+		return 0, fmt.Errorf("unknown open flags %#x", unknown)
+	}
+	if flags&FlagWrite == 0 {
+		return 0, errors.New("open flags must include FlagWrite")
+	}
+
+	flag := os.O_WRONLY
+	if flags&FlagRead != 0 {
+		flag = os.O_RDWR
+	}
+	if flags&FlagAppend != 0 {
+		flag |= os.O_APPEND
+	}
+	if flags&FlagCreate != 0 {
+		flag |= os.O_CREATE
+	}
+	if flags&FlagTrunc != 0 {
+		flag |= os.O_TRUNC
+	}
+	if flags&FlagExcl != 0 {
+		flag |= os.O_EXCL
+	}
+	return flag, nil
+}
+
+// UploadWithFlags writes srcbytes to dstPath, opening the remote file with
+// the given SSH_FXF_* bitmask (FlagWrite|FlagCreate|FlagTrunc is what
+// Upload uses)
+func (c *Connection) UploadWithFlags(srcbytes []byte, dstPath string, flags uint32) (err error) {
+	defer c.observe("uploadWithFlags", dstPath, time.Now(), &err)
+	return withTimeoutErr(c, func(ctx context.Context) error { return c.uploadWithFlags(ctx, srcbytes, dstPath, flags) })
+}
+
+// UploadExclusive writes srcbytes to dstPath, failing if the remote file
+// already exists
+func (c *Connection) UploadExclusive(srcbytes []byte, dstPath string) (err error) {
+	defer c.observe("uploadExclusive", dstPath, time.Now(), &err)
+	return withTimeoutErr(c, func(ctx context.Context) error {
+		return c.uploadWithFlags(ctx, srcbytes, dstPath, FlagWrite|FlagCreate|FlagExcl)
+	})
+}
+
+func (c *Connection) uploadWithFlags(ctx context.Context, srcbytes []byte, dstPath string, flags uint32) error {
+	if c.client() == nil {
+		return errors.New("not connected")
+	}
+
+	flag, err := osFlags(flags)
+	if err != nil {
+		return err
+	}
+
+	file, err := c.openFile(ctx, dstPath, flag)
+	if err != nil {
+		return fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := writeChunked(ctx, file, srcbytes); err != nil {
+		return fmt.Errorf("write to remote file: %w", err)
+	}
+
+	return nil
+}
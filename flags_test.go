@@ -0,0 +1,71 @@
+package sftp
+
+import "testing"
+
+// TestConnection_UploadWithFlags verifies SSH_FXF_* flags control how the
+// remote file is opened
+func TestConnection_UploadWithFlags(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("Create and truncate replaces content", func(t *testing.T) {
+		server.WriteFile(t, "/trunc.txt", []byte("old content"))
+		if err := conn.UploadWithFlags([]byte("new"), "/trunc.txt", FlagWrite|FlagCreate|FlagTrunc); err != nil {
+			t.Fatalf("UploadWithFlags failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/trunc.txt")); got != "new" {
+			t.Errorf("expected 'new', got %q", got)
+		}
+	})
+
+	t.Run("Append adds to the end", func(t *testing.T) {
+		server.WriteFile(t, "/append.log", []byte("line1\n"))
+		if err := conn.UploadWithFlags([]byte("line2\n"), "/append.log", FlagWrite|FlagAppend); err != nil {
+			t.Fatalf("UploadWithFlags failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/append.log")); got != "line1\nline2\n" {
+			t.Errorf("expected both lines, got %q", got)
+		}
+	})
+
+	t.Run("Without create a missing file fails", func(t *testing.T) {
+		if err := conn.UploadWithFlags([]byte("data"), "/missing.txt", FlagWrite); err == nil {
+			t.Error("expected error for missing file without FlagCreate, got nil")
+		}
+	})
+
+	t.Run("Flags without write are rejected", func(t *testing.T) {
+		if err := conn.UploadWithFlags([]byte("data"), "/ro.txt", FlagRead|FlagCreate); err == nil {
+			t.Error("expected error for flags without FlagWrite, got nil")
+		}
+	})
+
+	t.Run("Unknown flags are rejected", func(t *testing.T) {
+		if err := conn.UploadWithFlags([]byte("data"), "/bad.txt", FlagWrite|0x100); err == nil {
+			t.Error("expected error for unknown flag bits, got nil")
+		}
+	})
+
+	t.Run("UploadWithFlags returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).UploadWithFlags([]byte("data"), "/x", FlagWrite)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_UploadExclusive verifies existing files are never overwritten
+func TestConnection_UploadExclusive(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	if err := conn.UploadExclusive([]byte("first"), "/once.txt"); err != nil {
+		t.Fatalf("UploadExclusive failed: %v", err)
+	}
+	if err := conn.UploadExclusive([]byte("second"), "/once.txt"); err == nil {
+		t.Error("expected error for existing file, got nil")
+	}
+	if got := string(server.ReadFile(t, "/once.txt")); got != "first" {
+		t.Errorf("expected original content 'first', got %q", got)
+	}
+}
@@ -0,0 +1,52 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrKerberosUnavailable is returned by ConnectGSSAPI when no Kerberos
+// implementation is registered or it cannot produce credentials
+var ErrKerberosUnavailable = errors.New("kerberos unavailable")
+
+// GSSAPIClientFactory returns a GSS-API client holding Kerberos credentials
+// for username in realm, e.g. from the system credential cache
+type GSSAPIClientFactory func(username, realm string) (ssh.GSSAPIClient, error)
+
+var (
+	gssapiMu      sync.RWMutex
+	gssapiFactory GSSAPIClientFactory
+)
+
+// RegisterGSSAPIClient sets the Kerberos implementation used by
+// ConnectGSSAPI. The extension links no Kerberos library itself, since
+// that would need cgo or a large pure Go dependency for every build; custom
+// k6 builds that need Kerberos register one from an init function
+func RegisterGSSAPIClient(factory GSSAPIClientFactory) {
+	gssapiMu.Lock()
+	defer gssapiMu.Unlock()
+	gssapiFactory = factory
+}
+
+// ConnectGSSAPI connects with gssapi-with-mic (Kerberos) authentication,
+// as required by SFTP servers in Active Directory environments
+// Returns ErrKerberosUnavailable if no implementation is registered with
+// RegisterGSSAPIClient or it has no credentials for username
+func (c *Client) ConnectGSSAPI(host, username, realm string, port int) (*Connection, error) {
+	gssapiMu.RLock()
+	factory := gssapiFactory
+	gssapiMu.RUnlock()
+	if factory == nil {
+		return nil, fmt.Errorf("%w: no GSS-API implementation registered", ErrKerberosUnavailable)
+	}
+
+	gss, err := factory(username, realm)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKerberosUnavailable, err)
+	}
+
+	return c.Connect(host, username, "", port, WithAuthMethods(ssh.GSSAPIWithMICAuthMethod(gss, host)))
+}
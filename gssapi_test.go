@@ -0,0 +1,98 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeGSSAPIClient and fakeGSSAPIServer exchange a fixed token and sign
+// MICs by prefixing them, standing in for a Kerberos library
+type fakeGSSAPIClient struct {
+	principal string
+}
+
+func (c fakeGSSAPIClient) InitSecContext(string, []byte, bool) ([]byte, bool, error) {
+	return []byte(c.principal), false, nil
+}
+
+func (c fakeGSSAPIClient) GetMIC(micField []byte) ([]byte, error) {
+	return append([]byte("mic:"), micField...), nil
+}
+
+func (c fakeGSSAPIClient) DeleteSecContext() error { return nil }
+
+type fakeGSSAPIServer struct{}
+
+func (fakeGSSAPIServer) AcceptSecContext(token []byte) ([]byte, string, bool, error) {
+	return nil, string(token), false, nil
+}
+
+func (fakeGSSAPIServer) VerifyMIC(micField, micToken []byte) error {
+	if !bytes.Equal(micToken, append([]byte("mic:"), micField...)) {
+		return errors.New("bad mic")
+	}
+	return nil
+}
+
+func (fakeGSSAPIServer) DeleteSecContext() error { return nil }
+
+// TestClient_ConnectGSSAPI verifies Kerberos authentication goes through
+// the registered GSS-API implementation
+func TestClient_ConnectGSSAPI(t *testing.T) {
+	server := NewMockServerWith(t, func(s *MockServer) {
+		s.config.GSSAPIWithMICConfig = &ssh.GSSAPIWithMICConfig{
+			AllowLogin: func(_ ssh.ConnMetadata, srcName string) (*ssh.Permissions, error) {
+				if srcName != s.User+"@EXAMPLE.COM" {
+					return nil, errors.New("unknown principal")
+				}
+				return nil, nil
+			},
+			Server: fakeGSSAPIServer{},
+		}
+	})
+	t.Cleanup(func() { RegisterGSSAPIClient(nil) })
+
+	t.Run("Unregistered implementation returns ErrKerberosUnavailable", func(t *testing.T) {
+		RegisterGSSAPIClient(nil)
+		_, err := (&Client{}).ConnectGSSAPI(server.Host, server.User, "EXAMPLE.COM", server.Port)
+		if !errors.Is(err, ErrKerberosUnavailable) {
+			t.Errorf("expected ErrKerberosUnavailable, got: %v", err)
+		}
+	})
+
+	t.Run("Missing credentials return ErrKerberosUnavailable", func(t *testing.T) {
+		RegisterGSSAPIClient(func(string, string) (ssh.GSSAPIClient, error) {
+			return nil, errors.New("no credential cache")
+		})
+		_, err := (&Client{}).ConnectGSSAPI(server.Host, server.User, "EXAMPLE.COM", server.Port)
+		if !errors.Is(err, ErrKerberosUnavailable) {
+			t.Errorf("expected ErrKerberosUnavailable, got: %v", err)
+		}
+	})
+
+	t.Run("Registered implementation authenticates", func(t *testing.T) {
+		RegisterGSSAPIClient(func(username, realm string) (ssh.GSSAPIClient, error) {
+			return fakeGSSAPIClient{principal: username + "@" + realm}, nil
+		})
+		conn, err := (&Client{}).ConnectGSSAPI(server.Host, server.User, "EXAMPLE.COM", server.Port)
+		if err != nil {
+			t.Fatalf("ConnectGSSAPI failed: %v", err)
+		}
+		defer conn.Close()
+		if _, err := conn.Ls("/"); err != nil {
+			t.Errorf("Ls failed: %v", err)
+		}
+	})
+
+	t.Run("Rejected principal fails the handshake", func(t *testing.T) {
+		RegisterGSSAPIClient(func(string, string) (ssh.GSSAPIClient, error) {
+			return fakeGSSAPIClient{principal: "intruder@EXAMPLE.COM"}, nil
+		})
+		if _, err := (&Client{}).ConnectGSSAPI(server.Host, server.User, "EXAMPLE.COM", server.Port); err == nil {
+			t.Error("expected handshake to fail for a rejected principal")
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package sftp
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrUnknownHasher is returned for a checksum algorithm no Hasher is
+// registered for
+var ErrUnknownHasher = errors.New("unknown hash algorithm")
+
+// Hasher is a checksum algorithm usable by name, e.g. in HashFile
+type Hasher interface {
+	New() hash.Hash
+	Name() string
+}
+
+// MD5Hasher computes MD5 checksums, for servers that publish them; it is
+// not collision resistant
+type MD5Hasher struct{}
+
+// New returns a new MD5 hash
+func (MD5Hasher) New() hash.Hash { return md5.New() }
+
+// Name returns "md5"
+func (MD5Hasher) Name() string { return "md5" }
+
+// SHA1Hasher computes SHA-1 checksums
+type SHA1Hasher struct{}
+
+// New returns a new SHA-1 hash
+func (SHA1Hasher) New() hash.Hash { return sha1.New() }
+
+// Name returns "sha1"
+func (SHA1Hasher) Name() string { return "sha1" }
+
+// SHA256Hasher computes SHA-256 checksums
+type SHA256Hasher struct{}
+
+// New returns a new SHA-256 hash
+func (SHA256Hasher) New() hash.Hash { return sha256.New() }
+
+// Name returns "sha256"
+func (SHA256Hasher) Name() string { return "sha256" }
+
+// SHA512Hasher computes SHA-512 checksums
+type SHA512Hasher struct{}
+
+// New returns a new SHA-512 hash
+func (SHA512Hasher) New() hash.Hash { return sha512.New() }
+
+// Name returns "sha512"
+func (SHA512Hasher) Name() string { return "sha512" }
+
+var (
+	hashersMu sync.RWMutex
+	hashers   = map[string]Hasher{
+		"md5":    MD5Hasher{},
+		"sha1":   SHA1Hasher{},
+		"sha256": SHA256Hasher{},
+		"sha512": SHA512Hasher{},
+	}
+)
+
+// RegisterHasher makes h available under name, replacing any hasher
+// already registered there, e.g. to add xxHash or BLAKE3 from a custom
+// k6 build
+func RegisterHasher(name string, h Hasher) {
+	hashersMu.Lock()
+	defer hashersMu.Unlock()
+	hashers[name] = h
+}
+
+// lookupHasher returns the hasher registered under name
+func lookupHasher(name string) (Hasher, error) {
+	hashersMu.RLock()
+	defer hashersMu.RUnlock()
+
+	h, ok := hashers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownHasher, name)
+	}
+	return h, nil
+}
+
+// HashFile streams a remote file through the named hasher ("md5", "sha1",
+// "sha256", "sha512" or one added with RegisterHasher) and returns the hex
+// encoded checksum
+func (c *Connection) HashFile(remotePath, algorithm string) (_ string, err error) {
+	defer c.observe("hashFile", remotePath, time.Now(), &err)
+
+	if c.client() == nil {
+		return "", errors.New("not connected")
+	}
+
+	h, err := lookupHasher(algorithm)
+	if err != nil {
+		return "", err
+	}
+	return withReplay(c, func(ctx context.Context) (string, error) { return c.hashFile(ctx, remotePath, h) })
+}
+
+// hashFile returns the hex encoded checksum of a remote file
+func (c *Connection) hashFile(ctx context.Context, remotePath string, hasher Hasher) (string, error) {
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return "", fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	h := hasher.New()
+	if _, err := io.Copy(ctxWriter{ctx, h}, file); err != nil {
+		return "", fmt.Errorf("read remote file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
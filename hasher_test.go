@@ -0,0 +1,64 @@
+package sftp
+
+import (
+	"errors"
+	"hash"
+	"hash/crc32"
+	"testing"
+)
+
+// crc32Hasher is a custom Hasher for testing RegisterHasher
+type crc32Hasher struct{}
+
+func (crc32Hasher) New() hash.Hash { return crc32.NewIEEE() }
+func (crc32Hasher) Name() string   { return "crc32" }
+
+// TestConnection_HashFile verifies checksums for the built-in and
+// registered hashers
+func TestConnection_HashFile(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/data.bin", []byte("abc"))
+
+	builtin := map[string]string{
+		"md5":    "900150983cd24fb0d6963f7d28e17f72",
+		"sha1":   "a9993e364706816aba3e25717850c26c9cd0d89d",
+		"sha256": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		"sha512": "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+	}
+	for algorithm, want := range builtin {
+		t.Run("Built-in "+algorithm, func(t *testing.T) {
+			got, err := conn.HashFile("/data.bin", algorithm)
+			if err != nil {
+				t.Fatalf("HashFile failed: %v", err)
+			}
+			if got != want {
+				t.Errorf("expected %s, got %s", want, got)
+			}
+		})
+	}
+
+	t.Run("Registered hasher is used by name", func(t *testing.T) {
+		RegisterHasher("crc32", crc32Hasher{})
+		got, err := conn.HashFile("/data.bin", "crc32")
+		if err != nil {
+			t.Fatalf("HashFile failed: %v", err)
+		}
+		if got != "352441c2" {
+			t.Errorf("expected 352441c2, got %s", got)
+		}
+	})
+
+	t.Run("Unknown algorithm returns ErrUnknownHasher", func(t *testing.T) {
+		if _, err := conn.HashFile("/data.bin", "whirlpool"); !errors.Is(err, ErrUnknownHasher) {
+			t.Errorf("expected ErrUnknownHasher, got: %v", err)
+		}
+	})
+
+	t.Run("HashFile returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).HashFile("/data.bin", "sha256")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
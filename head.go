@@ -0,0 +1,77 @@
+package sftp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// HeadBytes returns the first n bytes of a remote file, or the whole file
+// if it is shorter, e.g. to check magic bytes
+func (c *Connection) HeadBytes(remotePath string, n int64) (_ []byte, err error) {
+	defer c.observe("headBytes", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) ([]byte, error) { return c.headBytes(ctx, remotePath, n) })
+}
+
+func (c *Connection) headBytes(ctx context.Context, remotePath string, n int64) ([]byte, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("invalid byte count %d", n)
+	}
+
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, n))
+	if err != nil {
+		return nil, fmt.Errorf("read remote file: %w", err)
+	}
+	return data, nil
+}
+
+// HeadLines returns the first nLines lines of a remote file without their
+// line endings, e.g. to check a CSV header, and stops reading once they
+// have been read
+func (c *Connection) HeadLines(remotePath string, nLines int) (_ []string, err error) {
+	defer c.observe("headLines", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) ([]string, error) { return c.headLines(ctx, remotePath, nLines) })
+}
+
+func (c *Connection) headLines(ctx context.Context, remotePath string, nLines int) ([]string, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	lines := []string{}
+	r := bufio.NewReader(file)
+	for len(lines) < nLines {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte{'\n'}), []byte{'\r'})
+			lines = append(lines, string(line))
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read remote file: %w", err)
+		}
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,77 @@
+package sftp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// TestConnection_HeadBytes verifies the first bytes are returned
+func TestConnection_HeadBytes(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	png := []byte("\x89PNG\r\n\x1a\nrest of the image")
+	server.WriteFile(t, "/image.png", png)
+
+	t.Run("First n bytes are returned", func(t *testing.T) {
+		got, err := conn.HeadBytes("/image.png", 8)
+		if err != nil {
+			t.Fatalf("HeadBytes failed: %v", err)
+		}
+		if !bytes.Equal(got, png[:8]) {
+			t.Errorf("expected %x, got %x", png[:8], got)
+		}
+	})
+
+	t.Run("Short file is returned whole", func(t *testing.T) {
+		got, err := conn.HeadBytes("/image.png", 1000)
+		if err != nil {
+			t.Fatalf("HeadBytes failed: %v", err)
+		}
+		if !bytes.Equal(got, png) {
+			t.Errorf("expected whole file, got %x", got)
+		}
+	})
+
+	t.Run("HeadBytes returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).HeadBytes("/image.png", 8)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_HeadLines verifies the first lines are returned without
+// line endings
+func TestConnection_HeadLines(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/data.csv", []byte("id,name\r\n1,alice\r\n2,bob"))
+
+	t.Run("First n lines are returned", func(t *testing.T) {
+		got, err := conn.HeadLines("/data.csv", 2)
+		if err != nil {
+			t.Fatalf("HeadLines failed: %v", err)
+		}
+		if want := []string{"id,name", "1,alice"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("Unterminated last line is included", func(t *testing.T) {
+		got, err := conn.HeadLines("/data.csv", 10)
+		if err != nil {
+			t.Fatalf("HeadLines failed: %v", err)
+		}
+		if want := []string{"id,name", "1,alice", "2,bob"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("HeadLines returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).HeadLines("/data.csv", 1)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package sftp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ServeHealthDashboard serves the pool's Stats as JSON on /health at addr,
+// e.g. for watching a pool with curl during a debug session
+// The server runs until the returned stop function is called
+func (p *Pool) ServeHealthDashboard(addr string) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen for health dashboard: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", p.serveHealth)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = server.Serve(listener)
+	}()
+
+	stop := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+		<-done
+	}
+	return stop, nil
+}
+
+// serveHealth writes the pool stats as JSON
+func (p *Pool) serveHealth(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(p.Stats())
+}
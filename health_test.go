@@ -0,0 +1,89 @@
+package sftp
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+)
+
+// TestPool_ServeHealthDashboard verifies /health reports the pool stats
+func TestPool_ServeHealthDashboard(t *testing.T) {
+	server := NewMockServer(t)
+	c := (&Module{}).NewModuleInstance(nil).(*Client)
+
+	opts := server.Options()
+	opts.Label = "worker"
+	pool, err := c.CreatePool(opts, 2)
+	if err != nil {
+		t.Fatalf("CreatePool failed: %v", err)
+	}
+	defer pool.Drain()
+
+	active, err := pool.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer pool.Release(active)
+	idle, err := pool.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	pool.Release(idle)
+
+	addr := freeAddr(t)
+	stop, err := pool.ServeHealthDashboard(addr)
+	if err != nil {
+		t.Fatalf("ServeHealthDashboard failed: %v", err)
+	}
+	defer stop()
+
+	t.Run("Health endpoint returns pool stats", func(t *testing.T) {
+		resp, err := http.Get("http://" + addr + "/health")
+		if err != nil {
+			t.Fatalf("GET /health failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected JSON content type, got %q", ct)
+		}
+		var stats PoolStats
+		if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+			t.Fatalf("decode stats: %v", err)
+		}
+		if stats.Size != 2 || stats.Active != 1 || stats.Idle != 1 || len(stats.Connections) != 2 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+		for _, info := range stats.Connections {
+			if info.Idle != (info.Label == idle.Label()) {
+				t.Errorf("unexpected idle state for %s: %+v", info.Label, info)
+			}
+		}
+	})
+
+	t.Run("Address in use returns error", func(t *testing.T) {
+		if _, err := pool.ServeHealthDashboard(addr); err == nil {
+			t.Error("expected error for address in use, got nil")
+		}
+	})
+
+	t.Run("Stop shuts the server down", func(t *testing.T) {
+		stop()
+		if _, err := http.Get("http://" + addr + "/health"); err == nil {
+			t.Error("expected request to fail after stop")
+		}
+	})
+}
+
+// freeAddr returns a local address that is free to listen on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
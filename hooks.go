@@ -0,0 +1,80 @@
+package sftp
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrConnectionLost is passed to OnDisconnect when the server closed the
+// connection without reporting an error
+var ErrConnectionLost = errors.New("connection lost")
+
+// Lifecycle hooks from ConnectionOptions run in their own goroutine so a
+// slow hook never stalls the operation that triggered it. They are Go-only:
+// JavaScript functions must not be called off the VU goroutine
+
+// notifyConnect calls the OnConnect hook for a newly established connection
+func (c *Connection) notifyConnect() {
+	if c.opts.OnConnect != nil {
+		go c.opts.OnConnect(c)
+	}
+}
+
+// watchDisconnect reports the SSH connection dropping without Close being
+// called to the OnDisconnect hook
+func (c *Connection) watchDisconnect() {
+	if c.opts.OnDisconnect == nil || c.sshClient == nil {
+		return
+	}
+
+	sshClient := c.sshClient
+	go func() {
+		err := sshClient.Wait()
+		if c.closing.Load() {
+			return
+		}
+		if err == nil {
+			err = ErrConnectionLost
+		}
+		c.notifyDisconnect(err)
+	}()
+}
+
+// notifyDisconnect calls the OnDisconnect hook, at most once per connection
+func (c *Connection) notifyDisconnect(err error) {
+	if c.opts.OnDisconnect == nil {
+		return
+	}
+	c.disconnectOnce.Do(func() {
+		go c.opts.OnDisconnect(c, err)
+	})
+}
+
+// observe reports a failed operation to the OnError hook and, with Debug
+// set, logs it
+// Deferred with a named error result: defer c.observe("upload", path, time.Now(), &err)
+func (c *Connection) observe(op, path string, start time.Time, err *error) {
+	c.observeTransfer(op, path, "", start, nil, err)
+}
+
+// observeTransfer is observe for operations moving data between a local
+// and a remote file, also logging localPath and the *n bytes transferred
+func (c *Connection) observeTransfer(op, remotePath, localPath string, start time.Time, n *int64, err *error) {
+	if *err != nil {
+		c.reportError(op, remotePath, *err)
+	}
+	if c.opts.Debug {
+		var transferred int64
+		if n != nil {
+			transferred = *n
+		}
+		c.logOperation(op, remotePath, localPath, transferred, time.Since(start), *err)
+	}
+}
+
+// reportError calls the OnError hook for a failed operation
+func (c *Connection) reportError(op, path string, err error) {
+	if c.opts.OnError != nil {
+		go c.opts.OnError(c, op, path, err)
+	}
+}
@@ -0,0 +1,106 @@
+package sftp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestConnection_Hooks verifies the lifecycle hooks fire for their events
+func TestConnection_Hooks(t *testing.T) {
+	type opError struct {
+		op, path string
+		err      error
+	}
+
+	server := NewMockServer(t)
+	c := &Client{}
+
+	connected := make(chan *Connection, 1)
+	disconnected := make(chan error, 2)
+	failed := make(chan opError, 4)
+
+	opts := server.Options()
+	opts.OnConnect = func(conn *Connection) { connected <- conn }
+	opts.OnDisconnect = func(_ *Connection, err error) { disconnected <- err }
+	opts.OnError = func(_ *Connection, op, path string, err error) { failed <- opError{op, path, err} }
+
+	t.Run("OnConnect and OnDisconnect on Close", func(t *testing.T) {
+		conn, err := c.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+
+		select {
+		case got := <-connected:
+			if got != conn {
+				t.Error("expected OnConnect to receive the new connection")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected OnConnect to be called")
+		}
+
+		conn.Close()
+		conn.Close()
+		select {
+		case err := <-disconnected:
+			if err != nil {
+				t.Errorf("expected nil error for a clean close, got: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected OnDisconnect to be called")
+		}
+		select {
+		case <-disconnected:
+			t.Error("expected OnDisconnect to be called only once")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("OnError receives operation and path", func(t *testing.T) {
+		conn, err := c.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer func() {
+			conn.Close()
+			<-disconnected
+		}()
+		<-connected
+
+		if _, err := conn.Stat("/missing.txt"); err == nil {
+			t.Fatal("expected Stat to fail")
+		}
+		select {
+		case got := <-failed:
+			if got.op != "stat" || got.path != "/missing.txt" || !errors.Is(got.err, ErrRemoteNotFound) {
+				t.Errorf("unexpected OnError call: %+v", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected OnError to be called")
+		}
+	})
+
+	t.Run("OnDisconnect when the server drops the connection", func(t *testing.T) {
+		dropping := NewMockServer(t)
+		dropOpts := opts
+		dropOpts.Host, dropOpts.Port = dropping.Host, dropping.Port
+
+		conn, err := c.ConnectWithOptions(dropOpts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer conn.Close()
+		<-connected
+
+		dropping.Close()
+		select {
+		case err := <-disconnected:
+			if err == nil {
+				t.Error("expected an error for a dropped connection")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected OnDisconnect to be called")
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package sftp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// LsJSON is Ls with the result additionally marshalled to JSON when
+// ConnectionOptions.JSONMode is set, so scripts can JSON.parse() it
+// The JSON string is empty when JSONMode is off
+func (c *Connection) LsJSON(path string) (*LsResult, string, error) {
+	results, err := c.Ls(path)
+	return withJSON(c, results, err)
+}
+
+// StatJSON is Stat with the result additionally marshalled to JSON when
+// ConnectionOptions.JSONMode is set
+func (c *Connection) StatJSON(remotePath string) (map[string]interface{}, string, error) {
+	info, err := c.Stat(remotePath)
+	return withJSON(c, info, err)
+}
+
+// StatManyJSON is StatMany with the result additionally marshalled to JSON
+// when ConnectionOptions.JSONMode is set
+func (c *Connection) StatManyJSON(paths []string) (map[string]map[string]interface{}, string, error) {
+	results, err := c.StatMany(paths)
+	return withJSON(c, results, err)
+}
+
+// LsRecursiveJSON is LsRecursive with the result additionally marshalled
+// to JSON when ConnectionOptions.JSONMode is set
+func (c *Connection) LsRecursiveJSON(remotePath string) ([]map[string]interface{}, string, error) {
+	entries, err := c.LsRecursive(remotePath)
+	return withJSON(c, entries, err)
+}
+
+// DiskUsageJSON is DiskUsage with the result additionally marshalled to
+// JSON when ConnectionOptions.JSONMode is set
+func (c *Connection) DiskUsageJSON(rootPath string, maxDepth int) (map[string]int64, string, error) {
+	usage, err := c.DiskUsage(rootPath, maxDepth)
+	return withJSON(c, usage, err)
+}
+
+// UploadStatJSON is UploadStat with the result additionally marshalled to
+// JSON when ConnectionOptions.JSONMode is set
+func (c *Connection) UploadStatJSON(data []byte, remotePath string) (map[string]interface{}, string, error) {
+	info, err := c.UploadStat(data, remotePath)
+	return withJSON(c, info, err)
+}
+
+// OwnershipReportJSON is OwnershipReport with the result additionally
+// marshalled to JSON when ConnectionOptions.JSONMode is set
+func (c *Connection) OwnershipReportJSON(remotePath string) ([]OwnershipEntry, string, error) {
+	entries, err := c.OwnershipReport(remotePath)
+	return withJSON(c, entries, err)
+}
+
+// withJSON marshals v when the connection is in JSON mode and the call
+// succeeded, passing v and err through unchanged
+func withJSON[T any](c *Connection, v T, err error) (T, string, error) {
+	if err != nil || !c.opts.JSONMode {
+		return v, "", err
+	}
+
+	data, jsonErr := json.Marshal(v)
+	if jsonErr != nil {
+		return v, "", fmt.Errorf("marshal json: %w", jsonErr)
+	}
+	return v, string(data), nil
+}
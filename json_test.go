@@ -0,0 +1,141 @@
+package sftp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestConnection_JSONMode verifies the *JSON variants marshal results only
+// when JSONMode is enabled
+func TestConnection_JSONMode(t *testing.T) {
+	server := NewMockServer(t)
+	server.WriteFile(t, "/dir/file.txt", []byte("hello"))
+
+	opts := server.Options()
+	opts.JSONMode = true
+	conn, err := (&Client{}).ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer conn.Close()
+
+	t.Run("LsJSON", func(t *testing.T) {
+		results, data, err := conn.LsJSON("/dir")
+		if err != nil {
+			t.Fatalf("LsJSON failed: %v", err)
+		}
+		if len(results.Entries) != 1 {
+			t.Fatalf("expected 1 entry, got %d", len(results.Entries))
+		}
+
+		var parsed struct {
+			Entries   []map[string]interface{} `json:"entries"`
+			Total     int                      `json:"total"`
+			Truncated bool                     `json:"truncated"`
+		}
+		if err := json.Unmarshal([]byte(data), &parsed); err != nil {
+			t.Fatalf("invalid JSON %q: %v", data, err)
+		}
+		if len(parsed.Entries) != 1 || parsed.Entries[0]["name"] != "file.txt" || parsed.Entries[0]["size"] != float64(5) {
+			t.Errorf("unexpected JSON entries: %v", parsed.Entries)
+		}
+		if parsed.Total != 1 || parsed.Truncated {
+			t.Errorf("unexpected JSON total %d (truncated=%v)", parsed.Total, parsed.Truncated)
+		}
+	})
+
+	t.Run("StatJSON", func(t *testing.T) {
+		_, data, err := conn.StatJSON("/dir/file.txt")
+		if err != nil {
+			t.Fatalf("StatJSON failed: %v", err)
+		}
+
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &parsed); err != nil {
+			t.Fatalf("invalid JSON %q: %v", data, err)
+		}
+		if parsed["isDir"] != false {
+			t.Errorf("unexpected JSON info: %v", parsed)
+		}
+	})
+
+	t.Run("StatManyJSON encodes missing paths as null", func(t *testing.T) {
+		_, data, err := conn.StatManyJSON([]string{"/dir/file.txt", "/missing.txt"})
+		if err != nil {
+			t.Fatalf("StatManyJSON failed: %v", err)
+		}
+
+		var parsed map[string]map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &parsed); err != nil {
+			t.Fatalf("invalid JSON %q: %v", data, err)
+		}
+		if parsed["/dir/file.txt"] == nil {
+			t.Error("expected info for /dir/file.txt")
+		}
+		if info, ok := parsed["/missing.txt"]; !ok || info != nil {
+			t.Errorf("expected null for /missing.txt, got %v", info)
+		}
+	})
+
+	t.Run("Tree and upload variants", func(t *testing.T) {
+		var entries []map[string]interface{}
+		_, data, err := conn.LsRecursiveJSON("/dir")
+		if err != nil {
+			t.Fatalf("LsRecursiveJSON failed: %v", err)
+		}
+		if err := json.Unmarshal([]byte(data), &entries); err != nil || len(entries) != 1 || entries[0]["path"] != "/dir/file.txt" {
+			t.Errorf("unexpected LsRecursiveJSON %q: %v", data, err)
+		}
+
+		var usage map[string]int64
+		_, data, err = conn.DiskUsageJSON("/dir", 0)
+		if err != nil {
+			t.Fatalf("DiskUsageJSON failed: %v", err)
+		}
+		if err := json.Unmarshal([]byte(data), &usage); err != nil || usage["/dir"] != 5 {
+			t.Errorf("unexpected DiskUsageJSON %q: %v", data, err)
+		}
+
+		var info map[string]interface{}
+		_, data, err = conn.UploadStatJSON([]byte("json"), "/up.txt")
+		if err != nil {
+			t.Fatalf("UploadStatJSON failed: %v", err)
+		}
+		if err := json.Unmarshal([]byte(data), &info); err != nil || info["size"] != float64(4) || info["createdAt"] == nil {
+			t.Errorf("unexpected UploadStatJSON %q: %v", data, err)
+		}
+
+		var report []map[string]interface{}
+		_, data, err = conn.OwnershipReportJSON("/dir")
+		if err != nil {
+			t.Fatalf("OwnershipReportJSON failed: %v", err)
+		}
+		if err := json.Unmarshal([]byte(data), &report); err != nil || len(report) == 0 || report[0]["path"] == nil {
+			t.Errorf("unexpected OwnershipReportJSON %q: %v", data, err)
+		}
+	})
+
+	t.Run("Errors skip marshalling", func(t *testing.T) {
+		_, data, err := conn.StatJSON("/missing.txt")
+		if err == nil {
+			t.Fatal("expected error for missing path, got nil")
+		}
+		if data != "" {
+			t.Errorf("expected empty JSON on error, got %q", data)
+		}
+	})
+
+	t.Run("JSON is empty when JSONMode is off", func(t *testing.T) {
+		plain := server.Connect(t)
+		results, data, err := plain.LsJSON("/dir")
+		if err != nil {
+			t.Fatalf("LsJSON failed: %v", err)
+		}
+		if data != "" {
+			t.Errorf("expected empty JSON without JSONMode, got %q", data)
+		}
+		if len(results.Entries) != 1 {
+			t.Errorf("expected 1 entry, got %d", len(results.Entries))
+		}
+	})
+}
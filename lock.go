@@ -0,0 +1,197 @@
+package sftp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+var (
+	// ErrAlreadyLocked is returned when a lock is held by another lease
+	ErrAlreadyLocked = errors.New("already locked")
+	// ErrLockNotHeld is returned by UnlockDir when the lock was released,
+	// expired and taken over, or never held by the given lease
+	ErrLockNotHeld = errors.New("lock not held")
+)
+
+// lockFileName is the sentinel LockDir creates inside the locked directory
+const lockFileName = ".lock"
+
+// LockHandle identifies a lock taken with LockDir or TryLockDir
+type LockHandle struct {
+	// Path is the remote sentinel file backing the lock
+	Path string `js:"path"`
+	// LeaseID is written into the sentinel to tell lock holders apart
+	LeaseID string `js:"leaseId"`
+}
+
+// lockRecord is the JSON content of a lock sentinel
+type lockRecord struct {
+	LeaseID  string        `json:"leaseId"`
+	Acquired time.Time     `json:"acquired"`
+	TTL      time.Duration `json:"ttl"`
+}
+
+// expired reports whether the lock's TTL has run out at now
+func (r lockRecord) expired(now time.Time) bool {
+	return !now.Before(r.Acquired.Add(r.TTL))
+}
+
+// TryLockDir locks dirPath by creating dirPath/.lock, returning
+// ErrAlreadyLocked immediately if another lease holds it
+// A lock older than its TTL (ConnectionOptions.LockTTL) is taken over. The
+// takeover is best effort: SFTP cannot replace a file conditionally, so two
+// clients taking over the same expired lock at once may both succeed
+func (c *Connection) TryLockDir(dirPath string) (_ LockHandle, err error) {
+	defer c.observe("tryLockDir", dirPath, time.Now(), &err)
+	return withTimeout(c, func(ctx context.Context) (LockHandle, error) { return c.tryLock(ctx, path.Join(dirPath, lockFileName)) })
+}
+
+// LockDir locks dirPath like TryLockDir, polling every
+// ConnectionOptions.PollInterval while it is held by another lease
+// Returns an error wrapping ErrAlreadyLocked if it is still held after timeout
+func (c *Connection) LockDir(dirPath string, timeout time.Duration) (_ LockHandle, err error) {
+	defer c.observe("lockDir", dirPath, time.Now(), &err)
+
+	interval := c.opts.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		lock, err := withTimeout(c, func(ctx context.Context) (LockHandle, error) { return c.tryLock(ctx, path.Join(dirPath, lockFileName)) })
+		if !errors.Is(err, ErrAlreadyLocked) {
+			return lock, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return LockHandle{}, fmt.Errorf("%w after waiting %s", err, timeout)
+		}
+		if err := c.sleep(min(interval, remaining)); err != nil {
+			return LockHandle{}, err
+		}
+	}
+}
+
+// UnlockDir releases a lock taken with LockDir or TryLockDir
+// Returns ErrLockNotHeld if the sentinel is gone or belongs to another lease
+func (c *Connection) UnlockDir(lock LockHandle) (err error) {
+	defer c.observe("unlockDir", lock.Path, time.Now(), &err)
+	return withTimeoutErr(c, func(ctx context.Context) error { return c.unlock(ctx, lock) })
+}
+
+func (c *Connection) unlock(ctx context.Context, lock LockHandle) error {
+	if c.client() == nil {
+		return errors.New("not connected")
+	}
+
+	held, err := c.readLock(ctx, lock.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s is not locked", ErrLockNotHeld, lock.Path)
+	}
+	if err != nil {
+		return err
+	}
+	if held.LeaseID != lock.LeaseID {
+		return fmt.Errorf("%w: %s is held by lease %s", ErrLockNotHeld, lock.Path, held.LeaseID)
+	}
+
+	if err := c.client().Remove(c.resolvePath(lock.Path)); err != nil {
+		return fmt.Errorf("remove lock: %w", err)
+	}
+	return nil
+}
+
+// IsWriteLocked reports whether a writer holds the lock on remotePath: the
+// directory holding it is locked with LockDir, so its .lock sentinel exists
+// and the TTL has not yet expired. Check it before reading a file another
+// process may be updating
+func (c *Connection) IsWriteLocked(remotePath string) (_ bool, err error) {
+	defer c.observe("isWriteLocked", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (bool, error) { return c.isWriteLocked(ctx, remotePath) })
+}
+
+func (c *Connection) isWriteLocked(ctx context.Context, remotePath string) (bool, error) {
+	if c.client() == nil {
+		return false, errors.New("not connected")
+	}
+
+	held, err := c.readLock(ctx, path.Join(path.Dir(remotePath), lockFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !held.expired(time.Now()), nil
+}
+
+// tryLock creates the sentinel at lockPath for a new lease, taking over an
+// expired lock once
+func (c *Connection) tryLock(ctx context.Context, lockPath string) (LockHandle, error) {
+	if c.client() == nil {
+		return LockHandle{}, errors.New("not connected")
+	}
+	lockPath = c.resolvePath(lockPath)
+
+	ttl := c.opts.LockTTL
+	if ttl <= 0 {
+		ttl = defaultLockTTL
+	}
+
+	var createErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		now := time.Now()
+		record := lockRecord{LeaseID: rand.Text(), Acquired: now.UTC(), TTL: ttl}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return LockHandle{}, fmt.Errorf("marshal lock: %w", err)
+		}
+
+		createErr = c.uploadWithFlags(ctx, data, lockPath, FlagWrite|FlagCreate|FlagExcl)
+		if createErr == nil {
+			return LockHandle{Path: lockPath, LeaseID: record.LeaseID}, nil
+		}
+
+		// Servers report an existing file as a generic failure, so look at
+		// the sentinel to tell a held lock from other errors
+		held, err := c.readLock(ctx, lockPath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// Released in the meantime, or the directory does not exist
+			continue
+		case err != nil:
+			return LockHandle{}, err
+		case !held.expired(now):
+			return LockHandle{}, fmt.Errorf("%w: %s held by lease %s until %s",
+				ErrAlreadyLocked, lockPath, held.LeaseID, held.Acquired.Add(held.TTL).Format(time.RFC3339))
+		}
+
+		if err := c.client().Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return LockHandle{}, fmt.Errorf("remove expired lock: %w", err)
+		}
+	}
+
+	return LockHandle{}, fmt.Errorf("create lock: %w", createErr)
+}
+
+// readLock reads and parses the lock sentinel at lockPath
+func (c *Connection) readLock(ctx context.Context, lockPath string) (lockRecord, error) {
+	var record lockRecord
+
+	data, err := c.readAll(ctx, lockPath)
+	if err != nil {
+		return record, err
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return record, fmt.Errorf("parse lock %s: %w", lockPath, err)
+	}
+	return record, nil
+}
@@ -0,0 +1,183 @@
+package sftp
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestConnection_LockDir verifies a directory lock excludes other leases
+// until it is released or expires
+func TestConnection_LockDir(t *testing.T) {
+	server := NewMockServer(t)
+	opts := server.Options()
+	opts.PollInterval = 10 * time.Millisecond
+	conn, err := (&Client{}).ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := conn.client().Mkdir("/shared"); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	t.Run("Held lock rejects TryLockDir", func(t *testing.T) {
+		lock, err := conn.TryLockDir("/shared")
+		if err != nil {
+			t.Fatalf("TryLockDir failed: %v", err)
+		}
+		if lock.Path != "/shared/.lock" || lock.LeaseID == "" {
+			t.Errorf("unexpected lock handle %+v", lock)
+		}
+
+		if _, err := conn.TryLockDir("/shared"); !errors.Is(err, ErrAlreadyLocked) {
+			t.Errorf("expected ErrAlreadyLocked, got: %v", err)
+		}
+
+		if err := conn.UnlockDir(lock); err != nil {
+			t.Fatalf("UnlockDir failed: %v", err)
+		}
+		if err := conn.UnlockDir(lock); !errors.Is(err, ErrLockNotHeld) {
+			t.Errorf("expected ErrLockNotHeld on second unlock, got: %v", err)
+		}
+	})
+
+	t.Run("LockDir waits for release", func(t *testing.T) {
+		lock, err := conn.TryLockDir("/shared")
+		if err != nil {
+			t.Fatalf("TryLockDir failed: %v", err)
+		}
+		time.AfterFunc(50*time.Millisecond, func() { conn.UnlockDir(lock) })
+
+		second, err := conn.LockDir("/shared", 5*time.Second)
+		if err != nil {
+			t.Fatalf("LockDir failed: %v", err)
+		}
+		if second.LeaseID == lock.LeaseID {
+			t.Error("expected a new lease")
+		}
+		if err := conn.UnlockDir(second); err != nil {
+			t.Fatalf("UnlockDir failed: %v", err)
+		}
+	})
+
+	t.Run("LockDir times out", func(t *testing.T) {
+		lock, err := conn.TryLockDir("/shared")
+		if err != nil {
+			t.Fatalf("TryLockDir failed: %v", err)
+		}
+		defer conn.UnlockDir(lock)
+
+		if _, err := conn.LockDir("/shared", 50*time.Millisecond); !errors.Is(err, ErrAlreadyLocked) {
+			t.Errorf("expected ErrAlreadyLocked, got: %v", err)
+		}
+	})
+
+	t.Run("Expired lock is taken over", func(t *testing.T) {
+		stale, _ := json.Marshal(lockRecord{
+			LeaseID:  "abandoned",
+			Acquired: time.Now().Add(-time.Hour),
+			TTL:      time.Minute,
+		})
+		server.WriteFile(t, "/shared/.lock", stale)
+
+		lock, err := conn.TryLockDir("/shared")
+		if err != nil {
+			t.Fatalf("TryLockDir failed: %v", err)
+		}
+		if lock.LeaseID == "abandoned" {
+			t.Error("expected a new lease")
+		}
+		if err := conn.UnlockDir(LockHandle{Path: lock.Path, LeaseID: "abandoned"}); !errors.Is(err, ErrLockNotHeld) {
+			t.Errorf("expected ErrLockNotHeld for the old lease, got: %v", err)
+		}
+		if err := conn.UnlockDir(lock); err != nil {
+			t.Fatalf("UnlockDir failed: %v", err)
+		}
+	})
+
+	t.Run("Missing directory returns error", func(t *testing.T) {
+		if _, err := conn.TryLockDir("/missing"); err == nil || errors.Is(err, ErrAlreadyLocked) {
+			t.Errorf("expected create error, got: %v", err)
+		}
+	})
+
+	t.Run("TryLockDir returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).TryLockDir("/shared")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_IsWriteLocked verifies a file counts as locked only while
+// its directory's sentinel's TTL has not expired
+func TestConnection_IsWriteLocked(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/reports/report.csv", []byte("a,b\n"))
+
+	writeSentinel := func(t *testing.T, acquired time.Time) {
+		t.Helper()
+		data, _ := json.Marshal(lockRecord{LeaseID: "writer", Acquired: acquired, TTL: time.Minute})
+		server.WriteFile(t, "/reports/.lock", data)
+	}
+
+	t.Run("No sentinel means unlocked", func(t *testing.T) {
+		locked, err := conn.IsWriteLocked("/reports/report.csv")
+		if err != nil || locked {
+			t.Errorf("expected unlocked, got %v (err=%v)", locked, err)
+		}
+	})
+
+	t.Run("LockDir locks the files in the directory", func(t *testing.T) {
+		lock, err := conn.LockDir("/reports", time.Second)
+		if err != nil {
+			t.Fatalf("LockDir failed: %v", err)
+		}
+		locked, err := conn.IsWriteLocked("/reports/report.csv")
+		if err != nil || !locked {
+			t.Errorf("expected locked while the directory is, got %v (err=%v)", locked, err)
+		}
+
+		if err := conn.UnlockDir(lock); err != nil {
+			t.Fatalf("UnlockDir failed: %v", err)
+		}
+		locked, err = conn.IsWriteLocked("/reports/report.csv")
+		if err != nil || locked {
+			t.Errorf("expected unlocked after UnlockDir, got %v (err=%v)", locked, err)
+		}
+	})
+
+	t.Run("Live sentinel means locked", func(t *testing.T) {
+		writeSentinel(t, time.Now())
+		locked, err := conn.IsWriteLocked("/reports/report.csv")
+		if err != nil || !locked {
+			t.Errorf("expected locked, got %v (err=%v)", locked, err)
+		}
+	})
+
+	t.Run("Expired sentinel means unlocked", func(t *testing.T) {
+		writeSentinel(t, time.Now().Add(-time.Hour))
+		locked, err := conn.IsWriteLocked("/reports/report.csv")
+		if err != nil || locked {
+			t.Errorf("expected unlocked, got %v (err=%v)", locked, err)
+		}
+	})
+
+	t.Run("Corrupt sentinel returns error", func(t *testing.T) {
+		server.WriteFile(t, "/reports/.lock", []byte("not json"))
+		if _, err := conn.IsWriteLocked("/reports/report.csv"); err == nil {
+			t.Error("expected error for a corrupt sentinel")
+		}
+	})
+
+	t.Run("IsWriteLocked returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).IsWriteLocked("/reports/report.csv")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package sftp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ManifestEntry is one file of an integrity manifest
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// GenerateManifest walks the tree rooted at remoteDirPath and returns a
+// JSON array of {path, sha256, size} objects for every regular file, in
+// walk order, like a package checksum file. Paths are absolute remote paths
+// so VerifyManifest can check them on any connection to the same server
+func (c *Connection) GenerateManifest(remoteDirPath string) (_ string, err error) {
+	defer c.observe("generateManifest", remoteDirPath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (string, error) { return c.generateManifest(ctx, remoteDirPath) })
+}
+
+func (c *Connection) generateManifest(ctx context.Context, remoteDirPath string) (string, error) {
+	if c.client() == nil {
+		return "", errors.New("not connected")
+	}
+	remoteDirPath = c.resolvePath(remoteDirPath)
+
+	entries := []ManifestEntry{}
+	walker := c.client().Walk(remoteDirPath)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if err := walker.Err(); err != nil {
+			return "", fmt.Errorf("walk %s: %w", walker.Path(), err)
+		}
+		info := walker.Stat()
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		digest, err := c.hashFile(ctx, walker.Path(), SHA256Hasher{})
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, ManifestEntry{Path: walker.Path(), SHA256: digest, Size: info.Size()})
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "", fmt.Errorf("marshal manifest: %w", err)
+	}
+	return string(data), nil
+}
+
+// VerifyManifest re-hashes every file listed in manifestJSON, as returned by
+// GenerateManifest, and returns the paths whose SHA-256 no longer matches,
+// including files that were deleted. An empty result means the tree is intact
+// Files created since the manifest was generated are not detected
+func (c *Connection) VerifyManifest(manifestJSON string) (_ []string, err error) {
+	defer c.observe("verifyManifest", "", time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) ([]string, error) { return c.verifyManifest(ctx, manifestJSON) })
+}
+
+func (c *Connection) verifyManifest(ctx context.Context, manifestJSON string) ([]string, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+
+	var entries []ManifestEntry
+	if err := json.Unmarshal([]byte(manifestJSON), &entries); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+
+	mismatched := []string{}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		digest, err := c.hashFile(ctx, entry.Path, SHA256Hasher{})
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			mismatched = append(mismatched, entry.Path)
+		case err != nil:
+			return nil, err
+		case digest != entry.SHA256:
+			mismatched = append(mismatched, entry.Path)
+		}
+	}
+	return mismatched, nil
+}
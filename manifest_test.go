@@ -0,0 +1,75 @@
+package sftp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestConnection_Manifest verifies a manifest lists every file and
+// verification reports changed and deleted ones
+func TestConnection_Manifest(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/pkg/a.txt", []byte("alpha"))
+	server.WriteFile(t, "/pkg/lib/b.txt", []byte("beta"))
+	server.WriteFile(t, "/pkg/lib/c.txt", []byte("gamma"))
+
+	manifest, err := conn.GenerateManifest("/pkg")
+	if err != nil {
+		t.Fatalf("GenerateManifest failed: %v", err)
+	}
+
+	t.Run("Manifest lists every file", func(t *testing.T) {
+		var entries []ManifestEntry
+		if err := json.Unmarshal([]byte(manifest), &entries); err != nil {
+			t.Fatalf("unmarshal manifest: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("expected 3 entries, got %d: %s", len(entries), manifest)
+		}
+		want := ManifestEntry{
+			Path:   "/pkg/a.txt",
+			SHA256: "8ed3f6ad685b959ead7022518e1af76cd816f8e8ec7ccdda1ed4018e8f2223f8",
+			Size:   5,
+		}
+		if entries[0] != want {
+			t.Errorf("expected %+v, got %+v", want, entries[0])
+		}
+	})
+
+	t.Run("Intact tree verifies", func(t *testing.T) {
+		mismatched, err := conn.VerifyManifest(manifest)
+		if err != nil || len(mismatched) != 0 {
+			t.Errorf("expected no mismatches, got %v (err=%v)", mismatched, err)
+		}
+	})
+
+	t.Run("Changed and deleted files are reported", func(t *testing.T) {
+		server.WriteFile(t, "/pkg/lib/b.txt", []byte("BETA"))
+		if err := conn.client().Remove("/pkg/lib/c.txt"); err != nil {
+			t.Fatalf("remove: %v", err)
+		}
+
+		mismatched, err := conn.VerifyManifest(manifest)
+		if err != nil {
+			t.Fatalf("VerifyManifest failed: %v", err)
+		}
+		if want := []string{"/pkg/lib/b.txt", "/pkg/lib/c.txt"}; !reflect.DeepEqual(mismatched, want) {
+			t.Errorf("expected %v, got %v", want, mismatched)
+		}
+	})
+
+	t.Run("Invalid manifest returns error", func(t *testing.T) {
+		if _, err := conn.VerifyManifest("not json"); err == nil {
+			t.Error("expected error for an invalid manifest")
+		}
+	})
+
+	t.Run("GenerateManifest returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).GenerateManifest("/pkg")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
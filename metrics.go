@@ -0,0 +1,110 @@
+package sftp
+
+import (
+	"io"
+	"time"
+
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/metrics"
+)
+
+// progressChunkSize is how much is written between two progress samples
+const progressChunkSize = 32 * 1024
+
+// sftpMetrics holds the custom k6 metrics emitted by the extension
+type sftpMetrics struct {
+	// transferProgress is the number of bytes transferred so far, tagged
+	// with remote_path, conn_id and label if the connection has one
+	transferProgress *metrics.Metric
+	// exported mirrors the metrics for ExportPrometheus
+	exported *prometheusMetrics
+}
+
+// registerMetrics registers the extension's metrics with the k6 registry
+// Outside of the init context, where no registry is available, only the
+// Prometheus mirror is set
+func registerMetrics(vu modules.VU, exported *prometheusMetrics) *sftpMetrics {
+	m := &sftpMetrics{exported: exported}
+	if vu == nil || vu.InitEnv() == nil || vu.InitEnv().Registry == nil {
+		return m
+	}
+
+	progress, err := vu.InitEnv().Registry.NewMetric("sftp_transfer_progress_bytes", metrics.Gauge, metrics.Data)
+	if err != nil {
+		vu.InitEnv().Logger.WithError(err).Warn("sftp: transfer progress metric disabled")
+		return m
+	}
+	m.transferProgress = progress
+	return m
+}
+
+// trackProgress wraps w to emit sftp_transfer_progress_bytes for
+// remotePath after every chunk written, if ConnectionOptions.ProgressMetrics
+// is set and the connection runs in a VU or ExportPrometheus is serving
+func (c *Connection) trackProgress(w io.Writer, remotePath string) io.Writer {
+	if !c.opts.ProgressMetrics || c.metrics == nil {
+		return w
+	}
+
+	p := &progressWriter{w: w, conn: c, remotePath: remotePath}
+	if state := c.vuState(); state != nil && c.vu.Context() != nil && c.metrics.transferProgress != nil {
+		tagsAndMeta := state.Tags.GetCurrentValues()
+		p.samples = state.Samples
+		p.tags = tagsAndMeta.Tags.With("remote_path", remotePath).With("conn_id", c.ConnectionID())
+		if label := c.Label(); label != "" {
+			p.tags = p.tags.With("label", label)
+		}
+		p.metadata = tagsAndMeta.Metadata
+	}
+	if p.samples == nil && !c.metrics.exported.active() {
+		return w
+	}
+	return p
+}
+
+// progressWriter writes in chunks of progressChunkSize and pushes the
+// running total after each
+type progressWriter struct {
+	w          io.Writer
+	conn       *Connection
+	remotePath string
+	samples    chan<- metrics.SampleContainer // nil outside of a VU
+	tags       *metrics.TagSet
+	metadata   map[string]string
+	written    int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	total := 0
+	for len(b) > 0 {
+		chunk := b[:min(len(b), progressChunkSize)]
+		n, err := p.w.Write(chunk)
+		total += n
+		p.written += int64(n)
+		p.push()
+		if err != nil {
+			return total, err
+		}
+		b = b[n:]
+	}
+	return total, nil
+}
+
+// push emits the bytes written so far
+func (p *progressWriter) push() {
+	if exported := p.conn.metrics.exported; exported.active() {
+		exported.transferProgress.WithLabelValues(p.remotePath).Set(float64(p.written))
+	}
+	if p.samples == nil {
+		return
+	}
+	metrics.PushIfNotDone(p.conn.vu.Context(), p.samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: p.conn.metrics.transferProgress,
+			Tags:   p.tags,
+		},
+		Time:     time.Now(),
+		Value:    float64(p.written),
+		Metadata: p.metadata,
+	})
+}
@@ -0,0 +1,118 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modulestest"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+)
+
+// TestConnection_ProgressMetrics verifies uploads and downloads emit the
+// running byte count per 32 KiB chunk when enabled
+func TestConnection_ProgressMetrics(t *testing.T) {
+	server := NewMockServer(t)
+	registry := metrics.NewRegistry()
+
+	vu := &modulestest.VU{
+		CtxField: context.Background(),
+		InitEnvField: &common.InitEnvironment{
+			TestPreInitState: &lib.TestPreInitState{Registry: registry, Logger: logrus.New()},
+		},
+	}
+	client, ok := (&Module{}).NewModuleInstance(vu).(*Client)
+	if !ok {
+		t.Fatal("expected instance to be *Client")
+	}
+	if client.metrics == nil || client.metrics.transferProgress == nil {
+		t.Fatal("expected metrics to be registered in the init context")
+	}
+
+	// Leave the init context, as k6 does before running the VU
+	samples := make(chan metrics.SampleContainer, 100)
+	vu.InitEnvField = nil
+	vu.StateField = &lib.State{
+		Samples: samples,
+		Tags:    lib.NewVUStateTags(registry.RootTagSet()),
+	}
+
+	connect := func(t *testing.T, progress bool) *Connection {
+		t.Helper()
+		opts := server.Options()
+		opts.ProgressMetrics = progress
+		opts.Label = "uploader"
+		conn, err := client.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	collect := func() []metrics.Sample {
+		var got []metrics.Sample
+		for {
+			select {
+			case container := <-samples:
+				got = append(got, container.GetSamples()...)
+			default:
+				return got
+			}
+		}
+	}
+
+	data := bytes.Repeat([]byte("x"), 100*1024)
+	want := []float64{32 * 1024, 64 * 1024, 96 * 1024, 100 * 1024}
+
+	t.Run("Upload emits progress per chunk", func(t *testing.T) {
+		conn := connect(t, true)
+		if err := conn.Upload(data, "/big.bin"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+
+		got := collect()
+		if len(got) != len(want) {
+			t.Fatalf("expected %d samples, got %d", len(want), len(got))
+		}
+		for i, sample := range got {
+			if sample.Metric.Name != "sftp_transfer_progress_bytes" || sample.Value != want[i] {
+				t.Errorf("sample %d: expected %v, got %s=%v", i, want[i], sample.Metric.Name, sample.Value)
+			}
+			if path, _ := sample.Tags.Get("remote_path"); path != "/big.bin" {
+				t.Errorf("sample %d: expected remote_path /big.bin, got %q", i, path)
+			}
+			if id, _ := sample.Tags.Get("conn_id"); id != conn.ConnectionID() {
+				t.Errorf("sample %d: expected conn_id %s, got %q", i, conn.ConnectionID(), id)
+			}
+			if label, _ := sample.Tags.Get("label"); label != "uploader" {
+				t.Errorf("sample %d: expected label uploader, got %q", i, label)
+			}
+		}
+	})
+
+	t.Run("Download emits progress", func(t *testing.T) {
+		err := connect(t, true).Download("/big.bin", filepath.Join(t.TempDir(), "big.bin"))
+		if err != nil {
+			t.Fatalf("Download failed: %v", err)
+		}
+
+		got := collect()
+		if len(got) == 0 || got[len(got)-1].Value != float64(len(data)) {
+			t.Errorf("expected progress ending at %d bytes, got %d samples", len(data), len(got))
+		}
+	})
+
+	t.Run("Nothing is emitted unless enabled", func(t *testing.T) {
+		if err := connect(t, false).Upload(data, "/quiet.bin"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if got := collect(); len(got) != 0 {
+			t.Errorf("expected no samples, got %d", len(got))
+		}
+	})
+}
@@ -0,0 +1,780 @@
+package sftp
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/fs"
+	mathrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// MockServer is an in-process SSH server exposing the SFTP subsystem over a
+// temporary local directory, so Connection can be tested against real
+// protocol traffic without an external server
+type MockServer struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	// Banner, when set, is sent to clients before authentication
+	Banner string
+	// AuthorizedKey, when set, is accepted for public key authentication
+	AuthorizedKey ssh.PublicKey
+	// Root is the local directory backing the remote "/"
+	Root string
+	// ServerCommand, when set, also starts the SFTP server for exec
+	// requests running exactly this command
+	ServerCommand string
+	// StallSFTP, when set, accepts SFTP session requests but never answers
+	// the SFTP handshake
+	StallSFTP bool
+	// ReadLatency and WriteLatency, when set, delay every read or write of
+	// an open file, so pipelined requests overlap
+	ReadLatency  time.Duration
+	WriteLatency time.Duration
+
+	listener net.Listener
+	config   *ssh.ServerConfig
+
+	mu        sync.Mutex
+	conns     []net.Conn
+	tlsConfig *tls.Config
+	failOpens map[string]int                 // remote path -> opens left to fail
+	failCmds  map[string]int                 // remote path -> commands left to fail
+	worm      map[string]error               // remote path -> error for non-append writes
+	xattrs    map[string][]sftp.StatExtended // remote path -> extended attributes
+	noXattrs  bool                           // reject extended attributes as unsupported
+	latency   time.Duration                  // mean delay added to every request
+	jitter    time.Duration                  // standard deviation of the delay
+	maxFiles  int                            // file quota, 0 for unlimited
+	handles   atomic.Int64                   // file handles currently open
+	reads     overlap                        // ReadAt calls on open files
+	writes    overlap                        // WriteAt calls on open files
+	sessions  map[ssh.Channel]struct{}       // channels serving SFTP
+	execs     map[string]string              // command -> output, see SetExecOutput
+	wg        sync.WaitGroup
+}
+
+// NewMockServer starts a MockServer that is shut down when the test ends
+// An ed25519 host key is generated when no host keys are given
+func NewMockServer(t testing.TB, hostKeys ...ssh.Signer) *MockServer {
+	t.Helper()
+	return NewMockServerWith(t, nil, hostKeys...)
+}
+
+// NewMockServerWith is NewMockServer with configure, if not nil, called
+// before the server accepts connections, for settings such as the SSH
+// server config that must not change once handshakes may read them
+func NewMockServerWith(t testing.TB, configure func(s *MockServer), hostKeys ...ssh.Signer) *MockServer {
+	t.Helper()
+
+	s := &MockServer{
+		User:     "testuser",
+		Password: "testpass",
+		Root:     t.TempDir(),
+	}
+
+	s.config = &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == s.User && string(password) == s.Password {
+				return nil, nil
+			}
+			return nil, errors.New("invalid credentials")
+		},
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == s.User && s.AuthorizedKey != nil && bytes.Equal(key.Marshal(), s.AuthorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized key")
+		},
+		BannerCallback: func(ssh.ConnMetadata) string {
+			return s.Banner
+		},
+	}
+
+	if len(hostKeys) == 0 {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generate host key: %v", err)
+		}
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			t.Fatalf("create host key signer: %v", err)
+		}
+		hostKeys = []ssh.Signer{signer}
+	}
+	for _, key := range hostKeys {
+		s.config.AddHostKey(key)
+	}
+	if configure != nil {
+		configure(s)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s.listener = listener
+
+	addr := listener.Addr().(*net.TCPAddr)
+	s.Host = addr.IP.String()
+	s.Port = addr.Port
+
+	s.wg.Add(1)
+	go s.serve()
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Options returns ConnectionOptions that authenticate against the server
+func (s *MockServer) Options() ConnectionOptions {
+	return ConnectionOptions{
+		Host:     s.Host,
+		Port:     s.Port,
+		Username: s.User,
+		Password: s.Password,
+	}
+}
+
+// Connect opens a Connection to the server, closed when the test ends
+func (s *MockServer) Connect(t testing.TB) *Connection {
+	t.Helper()
+
+	conn, err := (&Client{}).ConnectWithOptions(s.Options())
+	if err != nil {
+		t.Fatalf("connect to mock server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// SetTLS makes the server expect SSH tunnelled inside TLS on new connections
+func (s *MockServer) SetTLS(config *tls.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tlsConfig = config
+}
+
+// ServeWebSocket serves the SFTP protocol over WebSocket, without SSH, from
+// the same root and returns the ws:// URL. Clients must send the server's
+// credentials as basic auth
+func (s *MockServer) ServeWebSocket(t testing.TB) string {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != s.User || password != s.Password {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		rw := newWebSocketConn(ws)
+		defer rw.Close()
+
+		server := sftp.NewRequestServer(rw, s.handlers())
+		server.Serve()
+		server.Close()
+	}))
+	t.Cleanup(httpServer.Close)
+
+	return "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
+// FailOpens makes the next n attempts to open remotePath fail with a
+// generic SFTP failure, simulating a transient server error
+func (s *MockServer) FailOpens(remotePath string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failOpens == nil {
+		s.failOpens = make(map[string]int)
+	}
+	s.failOpens[filepath.Clean("/"+remotePath)] = n
+}
+
+// shouldFailOpen consumes one injected failure for remotePath, if any
+func (s *MockServer) shouldFailOpen(remotePath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	remotePath = filepath.Clean("/" + remotePath)
+	if s.failOpens[remotePath] <= 0 {
+		return false
+	}
+	s.failOpens[remotePath]--
+	return true
+}
+
+// FailCmds makes the next n commands on remotePath, such as Remove or a
+// Rename from it, fail with a generic SFTP failure
+func (s *MockServer) FailCmds(remotePath string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failCmds == nil {
+		s.failCmds = make(map[string]int)
+	}
+	s.failCmds[filepath.Clean("/"+remotePath)] = n
+}
+
+// shouldFailCmd consumes one injected command failure for remotePath, if any
+func (s *MockServer) shouldFailCmd(remotePath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	remotePath = filepath.Clean("/" + remotePath)
+	if s.failCmds[remotePath] <= 0 {
+		return false
+	}
+	s.failCmds[remotePath]--
+	return true
+}
+
+// RejectWrites makes opening remotePath for writing without O_APPEND fail
+// with err, emulating a WORM server, e.g. sftp.ErrSSHFxPermissionDenied
+func (s *MockServer) RejectWrites(remotePath string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.worm == nil {
+		s.worm = make(map[string]error)
+	}
+	s.worm[filepath.Clean("/"+remotePath)] = err
+}
+
+// writeRejection returns the error registered with RejectWrites, if any
+func (s *MockServer) writeRejection(remotePath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.worm[filepath.Clean("/"+remotePath)]
+}
+
+// DisableXattrs makes the server reject extended attributes in SETSTAT as
+// an unsupported operation
+func (s *MockServer) DisableXattrs() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noXattrs = true
+}
+
+// Xattrs returns the extended attributes last set on remotePath
+func (s *MockServer) Xattrs(remotePath string) []sftp.StatExtended {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.xattrs[filepath.Clean("/"+remotePath)]
+}
+
+// setXattrs stores extended attributes for remotePath, unless disabled
+func (s *MockServer) setXattrs(remotePath string, extended []sftp.StatExtended) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.noXattrs {
+		return sftp.ErrSSHFxOpUnsupported
+	}
+	if s.xattrs == nil {
+		s.xattrs = make(map[string][]sftp.StatExtended)
+	}
+	s.xattrs[filepath.Clean("/"+remotePath)] = extended
+	return nil
+}
+
+// SetLatency delays every SFTP request by a sample from a normal
+// distribution with the given mean and standard deviation, clamped to zero
+func (s *MockServer) SetLatency(mean, stddev time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency, s.jitter = mean, stddev
+}
+
+// delay sleeps for the configured latency, if any
+func (s *MockServer) delay() {
+	s.mu.Lock()
+	mean, stddev := s.latency, s.jitter
+	s.mu.Unlock()
+
+	if d := mean + time.Duration(mathrand.NormFloat64()*float64(stddev)); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// SetMaxFiles makes the server refuse to create files once n regular files
+// exist below Root, failing the open with SSH_FX_FAILURE like a quota on an
+// embedded server. Zero removes the limit
+func (s *MockServer) SetMaxFiles(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxFiles = n
+}
+
+// quotaExceeded reports whether creating remotePath would exceed the file quota
+func (s *MockServer) quotaExceeded(remotePath string) bool {
+	s.mu.Lock()
+	limit := s.maxFiles
+	s.mu.Unlock()
+	if limit <= 0 {
+		return false
+	}
+	if _, err := os.Stat(s.localPath(remotePath)); err == nil {
+		return false // overwriting an existing file
+	}
+
+	count := 0
+	filepath.WalkDir(s.Root, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			count++
+		}
+		return nil
+	})
+	return count >= limit
+}
+
+// MaxConcurrentReads returns the most reads of open files the server has
+// served at once, across all handles
+func (s *MockServer) MaxConcurrentReads() int {
+	return int(s.reads.peak.Load())
+}
+
+// MaxConcurrentWrites is MaxConcurrentReads for writes
+func (s *MockServer) MaxConcurrentWrites() int {
+	return int(s.writes.peak.Load())
+}
+
+// overlap counts calls in progress and the most there ever were at once
+type overlap struct {
+	current atomic.Int64
+	peak    atomic.Int64
+}
+
+// enter records a call starting; the returned function records its end
+func (o *overlap) enter() (exit func()) {
+	n := o.current.Add(1)
+	for {
+		peak := o.peak.Load()
+		if n <= peak || o.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	return func() { o.current.Add(-1) }
+}
+
+// OpenHandles returns how many file handles clients currently hold open
+func (s *MockServer) OpenHandles() int {
+	return int(s.handles.Load())
+}
+
+// trackHandle counts f as open until the client closes its handle
+func (s *MockServer) trackHandle(f sftp.WriterAtReaderAt) sftp.WriterAtReaderAt {
+	s.handles.Add(1)
+	return &trackedHandle{WriterAtReaderAt: f, server: s}
+}
+
+// trackedHandle is an open file counted by MockServer.OpenHandles
+type trackedHandle struct {
+	sftp.WriterAtReaderAt
+	server *MockServer
+	once   sync.Once
+}
+
+// ReadAt records how many reads overlap, see MaxConcurrentReads
+func (h *trackedHandle) ReadAt(p []byte, off int64) (int, error) {
+	defer h.server.reads.enter()()
+	time.Sleep(h.server.ReadLatency)
+	return h.WriterAtReaderAt.ReadAt(p, off)
+}
+
+// WriteAt records how many writes overlap, see MaxConcurrentWrites
+func (h *trackedHandle) WriteAt(p []byte, off int64) (int, error) {
+	defer h.server.writes.enter()()
+	time.Sleep(h.server.WriteLatency)
+	return h.WriterAtReaderAt.WriteAt(p, off)
+}
+
+func (h *trackedHandle) Close() error {
+	h.once.Do(func() { h.server.handles.Add(-1) })
+	if closer, ok := h.WriterAtReaderAt.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// SetExecOutput makes exec requests for command succeed with output, as a
+// server allowing shell commands would answer them
+func (s *MockServer) SetExecOutput(command, output string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.execs == nil {
+		s.execs = make(map[string]string)
+	}
+	s.execs[command] = output
+}
+
+// execOutput returns the output set for an exec request's command, if any
+func (s *MockServer) execOutput(req *ssh.Request) (string, bool) {
+	if req.Type != "exec" || len(req.Payload) < 4 {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	output, ok := s.execs[string(req.Payload[4:])]
+	return output, ok
+}
+
+// RestartSFTP closes every SFTP session, as a server restarting its SFTP
+// subsystem or ending idle sessions does, leaving the SSH connections open
+func (s *MockServer) RestartSFTP() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for channel := range s.sessions {
+		channel.Close()
+	}
+}
+
+// ConnCount returns the number of TCP connections the server has accepted
+func (s *MockServer) ConnCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// WriteFile creates a file on the server's filesystem
+func (s *MockServer) WriteFile(t testing.TB, remotePath string, data []byte) {
+	t.Helper()
+
+	local := s.localPath(remotePath)
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		t.Fatalf("create parent directory: %v", err)
+	}
+	if err := os.WriteFile(local, data, 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+}
+
+// ReadFile reads a file from the server's filesystem
+func (s *MockServer) ReadFile(t testing.TB, remotePath string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(s.localPath(remotePath))
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	return data
+}
+
+// Close stops the server and drops all client connections
+func (s *MockServer) Close() {
+	s.listener.Close()
+
+	s.mu.Lock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *MockServer) localPath(remotePath string) string {
+	return filepath.Join(s.Root, filepath.FromSlash(filepath.Clean("/"+remotePath)))
+}
+
+func (s *MockServer) serve() {
+	defer s.wg.Done()
+
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns = append(s.conns, netConn)
+		if s.tlsConfig != nil {
+			netConn = tls.Server(netConn, s.tlsConfig)
+		}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handleConn(netConn)
+	}
+}
+
+func (s *MockServer) handleConn(netConn net.Conn) {
+	defer s.wg.Done()
+	defer netConn.Close()
+
+	_, chans, reqs, err := ssh.NewServerConn(netConn, s.config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.handleSession(channel, requests)
+	}
+}
+
+func (s *MockServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer s.wg.Done()
+	defer channel.Close()
+
+	for req := range requests {
+		if output, ok := s.execOutput(req); ok {
+			req.Reply(true, nil)
+			go ssh.DiscardRequests(requests)
+			io.WriteString(channel, output)
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+			return
+		}
+		if !s.startsSFTP(req) {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+		go ssh.DiscardRequests(requests)
+
+		if s.StallSFTP {
+			io.Copy(io.Discard, channel)
+			return
+		}
+
+		s.mu.Lock()
+		if s.sessions == nil {
+			s.sessions = make(map[ssh.Channel]struct{})
+		}
+		s.sessions[channel] = struct{}{}
+		s.mu.Unlock()
+
+		server := sftp.NewRequestServer(channel, s.handlers())
+		server.Serve()
+		server.Close()
+
+		s.mu.Lock()
+		delete(s.sessions, channel)
+		s.mu.Unlock()
+		return
+	}
+}
+
+// startsSFTP reports whether a session request asks for the SFTP server:
+// the "sftp" subsystem, or an exec of ServerCommand if one is set
+func (s *MockServer) startsSFTP(req *ssh.Request) bool {
+	if len(req.Payload) < 4 {
+		return false
+	}
+	name := string(req.Payload[4:])
+	switch req.Type {
+	case "subsystem":
+		return name == "sftp"
+	case "exec":
+		return s.ServerCommand != "" && name == s.ServerCommand
+	}
+	return false
+}
+
+func (s *MockServer) handlers() sftp.Handlers {
+	h := &mockHandler{server: s}
+	return sftp.Handlers{FileGet: h, FilePut: h, FileCmd: h, FileList: h}
+}
+
+// mockHandler serves SFTP requests from the MockServer's root directory
+type mockHandler struct {
+	server *MockServer
+}
+
+func (h *mockHandler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	h.server.delay()
+	if h.server.shouldFailOpen(r.Filepath) {
+		return nil, sftp.ErrSSHFxFailure
+	}
+	f, err := os.Open(h.server.localPath(r.Filepath))
+	if err != nil {
+		return nil, err
+	}
+	return h.server.trackHandle(f), nil
+}
+
+func (h *mockHandler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	return h.openFile(r)
+}
+
+func (h *mockHandler) OpenFile(r *sftp.Request) (sftp.WriterAtReaderAt, error) {
+	return h.openFile(r)
+}
+
+func (h *mockHandler) openFile(r *sftp.Request) (sftp.WriterAtReaderAt, error) {
+	h.server.delay()
+	if h.server.shouldFailOpen(r.Filepath) {
+		return nil, sftp.ErrSSHFxFailure
+	}
+
+	pflags := r.Pflags()
+	if pflags.Creat && h.server.quotaExceeded(r.Filepath) {
+		return nil, sftp.ErrSSHFxFailure
+	}
+	if pflags.Write && !pflags.Append {
+		if err := h.server.writeRejection(r.Filepath); err != nil {
+			return nil, err
+		}
+	}
+
+	var flag int
+	switch {
+	case pflags.Read && pflags.Write:
+		flag = os.O_RDWR
+	case pflags.Write:
+		flag = os.O_WRONLY
+	}
+	if pflags.Creat {
+		flag |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flag |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flag |= os.O_EXCL
+	}
+	if pflags.Append {
+		flag |= os.O_APPEND
+	}
+
+	f, err := os.OpenFile(h.server.localPath(r.Filepath), flag, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if pflags.Append {
+		return h.server.trackHandle(appendFile{f}), nil
+	}
+	return h.server.trackHandle(f), nil
+}
+
+// appendFile ignores write offsets, as SSH_FXF_APPEND requires and
+// os.File.WriteAt refuses for files opened with O_APPEND
+type appendFile struct {
+	*os.File
+}
+
+func (f appendFile) WriteAt(p []byte, _ int64) (int, error) {
+	return f.Write(p)
+}
+
+func (h *mockHandler) Filecmd(r *sftp.Request) error {
+	h.server.delay()
+	if h.server.shouldFailCmd(r.Filepath) {
+		return sftp.ErrSSHFxFailure
+	}
+	local := h.server.localPath(r.Filepath)
+
+	switch r.Method {
+	case "Setstat":
+		attrs := r.Attributes()
+		flags := r.AttrFlags()
+		if flags.Size {
+			if err := os.Truncate(local, int64(attrs.Size)); err != nil {
+				return err
+			}
+		}
+		if flags.Permissions {
+			if err := os.Chmod(local, attrs.FileMode().Perm()); err != nil {
+				return err
+			}
+		}
+		if flags.Acmodtime {
+			if err := os.Chtimes(local, attrs.AccessTime(), attrs.ModTime()); err != nil {
+				return err
+			}
+		}
+		if len(attrs.Extended) > 0 {
+			return h.server.setXattrs(r.Filepath, attrs.Extended)
+		}
+		return nil
+	case "Rename", "PosixRename":
+		return os.Rename(local, h.server.localPath(r.Target))
+	case "Rmdir", "Remove":
+		return os.Remove(local)
+	case "Mkdir":
+		return os.Mkdir(local, 0o755)
+	case "Symlink":
+		return os.Symlink(r.Filepath, h.server.localPath(r.Target))
+	}
+
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+func (h *mockHandler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	h.server.delay()
+	local := h.server.localPath(r.Filepath)
+
+	switch r.Method {
+	case "List":
+		entries, err := os.ReadDir(local)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]os.FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return listerAt(infos), nil
+	case "Stat":
+		info, err := os.Stat(local)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	case "Lstat":
+		info, err := os.Lstat(local)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	}
+
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// listerAt serves a fixed slice of file infos to the request server
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
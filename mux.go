@@ -0,0 +1,86 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrTransportClosed is returned by MuxedTransport.Connect once the
+// transport has been closed
+var ErrTransportClosed = errors.New("transport closed")
+
+// MuxedTransport keeps one SSH connection open and opens each Connection as
+// its own SFTP session over it, like OpenSSH's ControlMaster
+// This skips the TCP and SSH handshakes for short-lived connections to the
+// same server. The transport must be closed once all its connections are
+type MuxedTransport struct {
+	client *Client
+	opts   ConnectionOptions
+
+	mu        sync.Mutex
+	sshClient *ssh.Client
+}
+
+// CreateMuxedTransport dials the SSH connection shared by the transport's
+// connections, using the same options as ConnectWithOptions
+// WebSocketURL is not supported, since a WebSocket carries a single session
+func (c *Client) CreateMuxedTransport(opts ConnectionOptions) (*MuxedTransport, error) {
+	opts = opts.withDefaults()
+	if opts.WebSocketURL != "" {
+		return nil, errors.New("muxed transport requires SSH, not a WebSocket")
+	}
+
+	sshClient, err := dialSSH(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MuxedTransport{
+		client:    c,
+		opts:      opts,
+		sshClient: sshClient,
+	}, nil
+}
+
+// Connect opens a new SFTP session over the shared SSH connection
+// The returned Connection's Close ends only that session
+func (m *MuxedTransport) Connect() (*Connection, error) {
+	m.mu.Lock()
+	sshClient := m.sshClient
+	m.mu.Unlock()
+	if sshClient == nil {
+		return nil, ErrTransportClosed
+	}
+
+	sftpClient, err := newSFTPClient(sshClient, m.opts)
+	if err != nil {
+		return nil, fmt.Errorf("sftp client creation failed: %w", err)
+	}
+
+	conn := m.client.newConnection(m.opts, sshClient, sftpClient)
+	conn.sharedSSH = true
+	conn.watchDisconnect()
+	conn.notifyConnect()
+	return conn, nil
+}
+
+// Close closes the shared SSH connection, ending the SFTP sessions of any
+// connections still open on it
+func (m *MuxedTransport) Close() error {
+	m.mu.Lock()
+	sshClient := m.sshClient
+	m.sshClient = nil
+	m.mu.Unlock()
+
+	if sshClient == nil {
+		return nil
+	}
+	if err := sshClient.Close(); err != nil {
+		return fmt.Errorf("ssh close: %w", err)
+	}
+	return nil
+}
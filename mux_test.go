@@ -0,0 +1,70 @@
+package sftp
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestMuxedTransport verifies connections share one SSH connection and
+// closing them leaves it open for the next
+func TestMuxedTransport(t *testing.T) {
+	server := NewMockServer(t)
+	client := &Client{}
+
+	transport, err := client.CreateMuxedTransport(server.Options())
+	if err != nil {
+		t.Fatalf("CreateMuxedTransport failed: %v", err)
+	}
+	t.Cleanup(func() { transport.Close() })
+
+	t.Run("Connections share one SSH connection", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			conn, err := transport.Connect()
+			if err != nil {
+				t.Fatalf("Connect %d failed: %v", i, err)
+			}
+			if err := conn.Upload([]byte("muxed"), "/muxed.txt"); err != nil {
+				t.Fatalf("Upload %d failed: %v", i, err)
+			}
+			if err := conn.Close(); err != nil {
+				t.Fatalf("Close %d failed: %v", i, err)
+			}
+		}
+
+		if got := server.ConnCount(); got != 1 {
+			t.Errorf("expected 1 TCP connection, got %d", got)
+		}
+		if got := string(server.ReadFile(t, "/muxed.txt")); got != "muxed" {
+			t.Errorf("expected 'muxed', got %q", got)
+		}
+	})
+
+	t.Run("Sessions can be open concurrently", func(t *testing.T) {
+		first, err := transport.Connect()
+		if err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer first.Close()
+		second, err := transport.Connect()
+		if err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer second.Close()
+
+		if err := first.Upload([]byte("one"), "/one.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if _, err := second.Stat("/one.txt"); err != nil {
+			t.Errorf("expected second session to see the upload, got: %v", err)
+		}
+	})
+
+	t.Run("Connect fails after Close", func(t *testing.T) {
+		if err := transport.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if _, err := transport.Connect(); !errors.Is(err, ErrTransportClosed) {
+			t.Errorf("expected ErrTransportClosed, got: %v", err)
+		}
+	})
+}
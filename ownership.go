@@ -0,0 +1,80 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// OwnershipEntry describes the owner of one remote file or directory
+// SFTP v3 carries only numeric IDs, so Owner and Group are UID and GID as
+// decimal strings
+type OwnershipEntry struct {
+	Path  string      `json:"path" js:"path"`
+	Owner string      `json:"owner" js:"owner"`
+	Group string      `json:"group" js:"group"`
+	UID   int         `json:"uid" js:"uid"`
+	GID   int         `json:"gid" js:"gid"`
+	Mode  os.FileMode `json:"mode" js:"mode"`
+}
+
+// OwnershipReport walks the tree rooted at remotePath and returns every
+// file and directory whose UID is not in ConnectionOptions.AllowedUIDs or
+// whose GID is not in AllowedGIDs, e.g. to check that uploads are owned by
+// the service account. An empty allow list is not checked, so with neither
+// set every entry is returned
+func (c *Connection) OwnershipReport(remotePath string) (_ []OwnershipEntry, err error) {
+	defer c.observe("ownershipReport", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) ([]OwnershipEntry, error) { return c.ownershipReport(ctx, remotePath) })
+}
+
+func (c *Connection) ownershipReport(ctx context.Context, remotePath string) ([]OwnershipEntry, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+	remotePath = c.resolvePath(remotePath)
+
+	allowedUIDs, allowedGIDs := c.opts.AllowedUIDs, c.opts.AllowedGIDs
+	checkAll := len(allowedUIDs) == 0 && len(allowedGIDs) == 0
+
+	entries := []OwnershipEntry{}
+	walker := c.client().Walk(remotePath)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := walker.Err(); err != nil {
+			return nil, fmt.Errorf("walk %s: %w", walker.Path(), err)
+		}
+
+		info := walker.Stat()
+		stat, ok := info.Sys().(*sftp.FileStat)
+		if !ok {
+			return nil, fmt.Errorf("%s: server did not report ownership", walker.Path())
+		}
+		uid, gid := int(stat.UID), int(stat.GID)
+
+		badUID := len(allowedUIDs) > 0 && !slices.Contains(allowedUIDs, uid)
+		badGID := len(allowedGIDs) > 0 && !slices.Contains(allowedGIDs, gid)
+		if !checkAll && !badUID && !badGID {
+			continue
+		}
+
+		entries = append(entries, OwnershipEntry{
+			Path:  walker.Path(),
+			Owner: strconv.Itoa(uid),
+			Group: strconv.Itoa(gid),
+			UID:   uid,
+			GID:   gid,
+			Mode:  info.Mode(),
+		})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,77 @@
+package sftp
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+// TestConnection_OwnershipReport verifies only entries owned outside the
+// allowed IDs are reported
+func TestConnection_OwnershipReport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the mock server reports no ownership on Windows")
+	}
+
+	server := NewMockServer(t)
+	uid, gid := os.Getuid(), os.Getgid()
+	if err := os.Mkdir(server.localPath("/uploads"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	server.WriteFile(t, "/uploads/a.txt", []byte("a"))
+	server.WriteFile(t, "/uploads/b.txt", []byte("b"))
+
+	connect := func(t *testing.T, uids, gids []int) *Connection {
+		t.Helper()
+		opts := server.Options()
+		opts.AllowedUIDs, opts.AllowedGIDs = uids, gids
+		conn, err := (&Client{}).ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	t.Run("Files owned by the allowed IDs are not reported", func(t *testing.T) {
+		entries, err := connect(t, []int{uid}, []int{gid}).OwnershipReport("/uploads")
+		if err != nil {
+			t.Fatalf("OwnershipReport failed: %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("expected no entries, got %+v", entries)
+		}
+	})
+
+	t.Run("Files owned by other IDs are reported", func(t *testing.T) {
+		entries, err := connect(t, []int{uid + 1}, nil).OwnershipReport("/uploads")
+		if err != nil {
+			t.Fatalf("OwnershipReport failed: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("expected the directory and both files, got %+v", entries)
+		}
+		for _, entry := range entries {
+			if entry.UID != uid || entry.GID != gid {
+				t.Errorf("expected %d:%d, got %+v", uid, gid, entry)
+			}
+		}
+	})
+
+	t.Run("Without allow lists every entry is reported", func(t *testing.T) {
+		entries, err := connect(t, nil, nil).OwnershipReport("/uploads/a.txt")
+		if err != nil {
+			t.Fatalf("OwnershipReport failed: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Path != "/uploads/a.txt" || !entries[0].Mode.IsRegular() {
+			t.Errorf("expected one regular file entry, got %+v", entries)
+		}
+	})
+
+	t.Run("OwnershipReport returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).OwnershipReport("/uploads")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/sftp"
+)
+
+// ErrWriteNotAllowed is returned by uploads with
+// ConnectionOptions.CheckWritePermission set when the destination directory
+// is not writable by the SSH user
+var ErrWriteNotAllowed = errors.New("write not allowed")
+
+// checkWritePermission returns ErrWriteNotAllowed if the parent directory of
+// dstPath does not grant the SSH user write and search permission
+// Supplementary groups are not known, see identity, so a directory writable
+// only through such a group is reported as not writable
+func (c *Connection) checkWritePermission(dstPath string) error {
+	uid, gid, err := c.identity()
+	if err != nil {
+		return err
+	}
+	if uid == 0 {
+		return nil
+	}
+
+	dir := path.Dir(dstPath)
+	info, err := c.client().Stat(c.resolvePath(dir))
+	if err != nil {
+		return fmt.Errorf("stat remote directory: %w", err)
+	}
+	stat, ok := info.Sys().(*sftp.FileStat)
+	if !ok {
+		return fmt.Errorf("%s: server did not report ownership", dir)
+	}
+
+	var need os.FileMode
+	switch {
+	case stat.UID == uid:
+		need = 0o300
+	case stat.GID == gid:
+		need = 0o030
+	default:
+		need = 0o003
+	}
+	if info.Mode().Perm()&need != need {
+		return fmt.Errorf("%w: %s is %s, owned by %d:%d, and the user is %d:%d",
+			ErrWriteNotAllowed, dir, info.Mode().Perm(), stat.UID, stat.GID, uid, gid)
+	}
+	return nil
+}
+
+// identityCommand prints the SSH user's UID and GID, one per line
+const identityCommand = "id -u && id -g"
+
+// identity returns the SSH user's UID and GID, which SFTP does not report:
+// ConnectionOptions.RemoteIdentity if set, otherwise the output of
+// identityCommand, or failing that the owner of the login directory
+// A root-owned login directory is usually a chroot rather than a root login,
+// so it is an error instead of an identity that skips the check. Only a
+// looked up identity is kept; errors are retried on the next call
+func (c *Connection) identity() (uid, gid uint32, err error) {
+	c.identityMu.Lock()
+	defer c.identityMu.Unlock()
+	if c.identityKnown {
+		return c.uid, c.gid, nil
+	}
+
+	switch {
+	case c.opts.RemoteIdentity != "":
+		uid, gid, err = parseIdentity(c.opts.RemoteIdentity, ":")
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid remoteIdentity %q: %w", c.opts.RemoteIdentity, err)
+		}
+	default:
+		var execErr error
+		if uid, gid, execErr = c.execIdentity(); execErr != nil {
+			uid, gid, err = c.loginDirOwner()
+			if err != nil {
+				return 0, 0, err
+			}
+			if uid == 0 {
+				return 0, 0, fmt.Errorf("cannot tell the SSH user's identity: %s failed (%v) and the login directory is owned by root, set remoteIdentity",
+					identityCommand, execErr)
+			}
+		}
+	}
+
+	c.uid, c.gid, c.identityKnown = uid, gid, true
+	return uid, gid, nil
+}
+
+// execIdentity runs identityCommand over SSH
+func (c *Connection) execIdentity() (uid, gid uint32, err error) {
+	if c.sshClient == nil {
+		return 0, 0, errors.New("no SSH connection")
+	}
+	session, err := c.sshClient.NewSession()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer session.Close()
+	out, err := session.Output(identityCommand)
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseIdentity(strings.TrimSpace(string(out)), "\n")
+}
+
+// loginDirOwner returns the owner of the login directory
+func (c *Connection) loginDirOwner() (uid, gid uint32, err error) {
+	wd, err := c.client().Getwd()
+	if err != nil {
+		return 0, 0, fmt.Errorf("resolve login directory: %w", err)
+	}
+	info, err := c.client().Stat(wd)
+	if err != nil {
+		return 0, 0, fmt.Errorf("stat login directory: %w", err)
+	}
+	stat, ok := info.Sys().(*sftp.FileStat)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s: server did not report ownership", wd)
+	}
+	return stat.UID, stat.GID, nil
+}
+
+// parseIdentity parses a UID and GID separated by sep, e.g. "1000:1000"
+func parseIdentity(s, sep string) (uid, gid uint32, err error) {
+	uidStr, gidStr, ok := strings.Cut(s, sep)
+	if !ok {
+		return 0, 0, fmt.Errorf("expected a uid and gid separated by %q, got %q", sep, s)
+	}
+	u, err := strconv.ParseUint(strings.TrimSpace(uidStr), 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("uid: %w", err)
+	}
+	g, err := strconv.ParseUint(strings.TrimSpace(gidStr), 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("gid: %w", err)
+	}
+	return uint32(u), uint32(g), nil
+}
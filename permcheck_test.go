@@ -0,0 +1,125 @@
+package sftp
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestConnection_CheckWritePermission verifies uploads into a directory the
+// user cannot write fail with ErrWriteNotAllowed before sending data
+func TestConnection_CheckWritePermission(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the mock server reports no ownership on Windows")
+	}
+
+	server := NewMockServer(t)
+	readOnly := server.localPath("/readonly")
+	if err := os.Mkdir(readOnly, 0o555); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(readOnly, 0o755) })
+	if os.Getuid() == 0 {
+		// Root may write anywhere, so act as an unprivileged owner
+		const owner = 12345
+		for _, p := range []string{server.Root, readOnly} {
+			if err := os.Chown(p, owner, owner); err != nil {
+				t.Fatalf("chown: %v", err)
+			}
+		}
+	}
+
+	opts := server.Options()
+	opts.CheckWritePermission = true
+	conn, err := (&Client{}).ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	t.Run("Read-only directory is rejected", func(t *testing.T) {
+		err := conn.Upload([]byte("data"), "/readonly/file.txt")
+		if !errors.Is(err, ErrWriteNotAllowed) {
+			t.Errorf("expected ErrWriteNotAllowed, got: %v", err)
+		}
+		if _, err := os.Stat(server.localPath("/readonly/file.txt")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected no file to be created, got: %v", err)
+		}
+	})
+
+	t.Run("Writable directory is accepted", func(t *testing.T) {
+		if err := conn.Upload([]byte("data"), "/file.txt"); err != nil {
+			t.Errorf("expected upload to succeed, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_WriteIdentity verifies how the SSH user's identity is found
+// for the write permission check
+func TestConnection_WriteIdentity(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the mock server reports no ownership on Windows")
+	}
+
+	server := NewMockServer(t)
+	readOnly := server.localPath("/readonly")
+	if err := os.Mkdir(readOnly, 0o555); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(readOnly, 0o755) })
+	if err := os.Mkdir(server.localPath("/shared"), 0o777); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Chmod(server.localPath("/shared"), 0o777); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	connect := func(t *testing.T, remoteIdentity string) *Connection {
+		t.Helper()
+		opts := server.Options()
+		opts.CheckWritePermission = true
+		opts.RemoteIdentity = remoteIdentity
+		conn, err := (&Client{}).ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	t.Run("RemoteIdentity is checked against the directory", func(t *testing.T) {
+		conn := connect(t, "12345:12345")
+		if err := conn.Upload([]byte("data"), "/readonly/file.txt"); !errors.Is(err, ErrWriteNotAllowed) {
+			t.Errorf("expected ErrWriteNotAllowed, got: %v", err)
+		}
+		if err := conn.Upload([]byte("data"), "/shared/file.txt"); err != nil {
+			t.Errorf("expected upload to a world-writable directory to succeed, got: %v", err)
+		}
+	})
+
+	t.Run("Invalid RemoteIdentity returns error", func(t *testing.T) {
+		conn := connect(t, "alice")
+		if err := conn.Upload([]byte("data"), "/shared/file.txt"); err == nil || !strings.Contains(err.Error(), "invalid remoteIdentity") {
+			t.Errorf("expected invalid remoteIdentity error, got: %v", err)
+		}
+	})
+
+	t.Run("Root-owned login directory is not taken as root", func(t *testing.T) {
+		if os.Getuid() != 0 {
+			t.Skip("needs root to leave the login directory owned by root")
+		}
+		conn := connect(t, "")
+		err := conn.Upload([]byte("data"), "/readonly/file.txt")
+		if err == nil || !strings.Contains(err.Error(), "set remoteIdentity") {
+			t.Errorf("expected an error asking for remoteIdentity, got: %v", err)
+		}
+
+		// The failed lookup is not cached, so id over SSH is tried again
+		server.SetExecOutput(identityCommand, "12345\n12345\n")
+		if err := conn.Upload([]byte("data"), "/readonly/file.txt"); !errors.Is(err, ErrWriteNotAllowed) {
+			t.Errorf("expected ErrWriteNotAllowed once id answers, got: %v", err)
+		}
+	})
+}
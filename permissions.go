@@ -0,0 +1,53 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// RecursiveChmod walks the remote tree rooted at remotePath and sets
+// fileMode on every regular file and dirMode on every directory, including
+// remotePath itself. Other node types such as symlinks are left alone
+// Failures do not stop the walk; they are joined into the returned error
+func (c *Connection) RecursiveChmod(remotePath string, fileMode, dirMode os.FileMode) (err error) {
+	defer c.observe("recursiveChmod", remotePath, time.Now(), &err)
+	return withReplayErr(c, func(ctx context.Context) error { return c.recursiveChmod(ctx, remotePath, fileMode, dirMode) })
+}
+
+func (c *Connection) recursiveChmod(ctx context.Context, remotePath string, fileMode, dirMode os.FileMode) error {
+	if c.client() == nil {
+		return errors.New("not connected")
+	}
+	remotePath = c.resolvePath(remotePath)
+
+	var errs []error
+	walker := c.client().Walk(remotePath)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := walker.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("walk %s: %w", walker.Path(), err))
+			continue
+		}
+
+		var mode os.FileMode
+		switch info := walker.Stat(); {
+		case info.IsDir():
+			mode = dirMode
+		case info.Mode().IsRegular():
+			mode = fileMode
+		default:
+			continue
+		}
+
+		if err := c.client().Chmod(walker.Path(), mode); err != nil {
+			errs = append(errs, fmt.Errorf("chmod %s: %w", walker.Path(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+package sftp
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestConnection_RecursiveChmod verifies files and directories in a tree get
+// their respective modes
+func TestConnection_RecursiveChmod(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	server.WriteFile(t, "/tree/a.txt", []byte("a"))
+	server.WriteFile(t, "/tree/sub/b.txt", []byte("b"))
+	server.WriteFile(t, "/tree/sub/deeper/c.txt", []byte("c"))
+
+	t.Run("Modes are applied to the whole tree", func(t *testing.T) {
+		if err := conn.RecursiveChmod("/tree", 0o640, 0o750); err != nil {
+			t.Fatalf("RecursiveChmod failed: %v", err)
+		}
+
+		want := map[string]os.FileMode{
+			"/tree":                  0o750,
+			"/tree/a.txt":            0o640,
+			"/tree/sub":              0o750,
+			"/tree/sub/b.txt":        0o640,
+			"/tree/sub/deeper":       0o750,
+			"/tree/sub/deeper/c.txt": 0o640,
+		}
+		for path, mode := range want {
+			info, err := os.Stat(server.localPath(path))
+			if err != nil {
+				t.Fatalf("stat %s: %v", path, err)
+			}
+			if got := info.Mode().Perm(); got != mode {
+				t.Errorf("expected %s to have mode %o, got %o", path, mode, got)
+			}
+		}
+	})
+
+	t.Run("Missing root returns error", func(t *testing.T) {
+		err := conn.RecursiveChmod("/missing", 0o640, 0o750)
+		if err == nil || !strings.Contains(err.Error(), "/missing") {
+			t.Errorf("expected error naming /missing, got: %v", err)
+		}
+	})
+
+	t.Run("RecursiveChmod returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).RecursiveChmod("/tree", 0o640, 0o750)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Pipeline copies a remote file from one connection to another, applying
+// transforms on the way, e.g. to test an ETL flow between two servers
+// Configure it with Source, Sink and optionally Transforms, then Execute
+type Pipeline struct {
+	srcConn    *Connection
+	remoteSrc  string
+	dstConn    *Connection
+	remoteDst  string
+	transforms []func([]byte) ([]byte, error)
+}
+
+// NewPipeline returns an empty Pipeline
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Source sets the file the pipeline reads
+func (p *Pipeline) Source(srcConn *Connection, remoteSrc string) *Pipeline {
+	p.srcConn = srcConn
+	p.remoteSrc = remoteSrc
+	return p
+}
+
+// Sink sets the file the pipeline writes
+func (p *Pipeline) Sink(dstConn *Connection, remoteDst string) *Pipeline {
+	p.dstConn = dstConn
+	p.remoteDst = remoteDst
+	return p
+}
+
+// Transforms appends functions applied to the content in order
+func (p *Pipeline) Transforms(fns ...func([]byte) ([]byte, error)) *Pipeline {
+	p.transforms = append(p.transforms, fns...)
+	return p
+}
+
+// Execute copies the source to the sink and returns the bytes written
+// Without transforms the file is streamed; transforms work on whole
+// buffers, so with any set the source is read into memory first. Nothing
+// is written if a transform fails
+func (p *Pipeline) Execute() (int64, error) {
+	if p.srcConn == nil {
+		return 0, errors.New("pipeline has no source")
+	}
+	if p.dstConn == nil {
+		return 0, errors.New("pipeline has no sink")
+	}
+	if p.srcConn.client() == nil || p.dstConn.client() == nil {
+		return 0, errors.New("not connected")
+	}
+
+	if len(p.transforms) == 0 {
+		return withReplay(p.dstConn, p.stream)
+	}
+
+	// Transforms may be JavaScript functions, so only the transfers run
+	// inside withTimeout
+	data, err := withReplay(p.srcConn, func(ctx context.Context) ([]byte, error) { return p.srcConn.readAll(ctx, p.remoteSrc) })
+	if err != nil {
+		p.srcConn.reportError("pipeline", p.remoteSrc, err)
+		return 0, err
+	}
+
+	for i, transform := range p.transforms {
+		if data, err = transform(data); err != nil {
+			return 0, fmt.Errorf("transform %d: %w", i, err)
+		}
+	}
+
+	if err := withReplayErr(p.dstConn, func(ctx context.Context) error { return p.dstConn.upload(ctx, data, p.remoteDst) }); err != nil {
+		p.dstConn.reportError("pipeline", p.remoteDst, err)
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// stream copies the source to the sink without buffering it
+func (p *Pipeline) stream(ctx context.Context) (int64, error) {
+	src, err := p.srcConn.open(ctx, p.remoteSrc)
+	if err != nil {
+		p.srcConn.reportError("pipeline", p.remoteSrc, err)
+		return 0, fmt.Errorf("open source file: %w", err)
+	}
+	defer src.Close()
+
+	n, err := p.dstConn.uploadFrom(ctx, src, p.remoteDst)
+	if err != nil {
+		p.dstConn.reportError("pipeline", p.remoteDst, err)
+		return n, err
+	}
+	return n, nil
+}
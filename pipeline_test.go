@@ -0,0 +1,69 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestPipeline verifies content flows from source through the transforms
+// into the sink
+func TestPipeline(t *testing.T) {
+	srcServer, dstServer := NewMockServer(t), NewMockServer(t)
+	src, dst := srcServer.Connect(t), dstServer.Connect(t)
+	srcServer.WriteFile(t, "/in.csv", []byte("a,b\n1,2\n"))
+
+	t.Run("Without transforms the file is copied", func(t *testing.T) {
+		n, err := NewPipeline().Source(src, "/in.csv").Sink(dst, "/copy.csv").Execute()
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if n != 8 {
+			t.Errorf("expected 8 bytes written, got %d", n)
+		}
+		if got := string(dstServer.ReadFile(t, "/copy.csv")); got != "a,b\n1,2\n" {
+			t.Errorf("unexpected sink content %q", got)
+		}
+	})
+
+	t.Run("Transforms are applied in order", func(t *testing.T) {
+		n, err := NewPipeline().
+			Source(src, "/in.csv").
+			Transforms(
+				func(data []byte) ([]byte, error) { return bytes.ToUpper(data), nil },
+				func(data []byte) ([]byte, error) { return bytes.ReplaceAll(data, []byte(","), []byte(";")), nil },
+			).
+			Sink(dst, "/out.csv").
+			Execute()
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if got := string(dstServer.ReadFile(t, "/out.csv")); got != "A;B\n1;2\n" {
+			t.Errorf("unexpected sink content %q", got)
+		}
+		if n != 8 {
+			t.Errorf("expected 8 bytes written, got %d", n)
+		}
+	})
+
+	t.Run("Failing transform writes nothing", func(t *testing.T) {
+		errBad := errors.New("bad row")
+		_, err := NewPipeline().
+			Source(src, "/in.csv").
+			Transforms(func([]byte) ([]byte, error) { return nil, errBad }).
+			Sink(dst, "/failed.csv").
+			Execute()
+		if !errors.Is(err, errBad) {
+			t.Errorf("expected transform error, got: %v", err)
+		}
+		if _, err := dst.Stat("/failed.csv"); !errors.Is(err, ErrRemoteNotFound) {
+			t.Errorf("expected no sink file, got: %v", err)
+		}
+	})
+
+	t.Run("Missing sink returns error", func(t *testing.T) {
+		if _, err := NewPipeline().Source(src, "/in.csv").Execute(); err == nil {
+			t.Error("expected error without a sink")
+		}
+	})
+}
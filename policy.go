@@ -0,0 +1,46 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// ErrPathDenied is returned when a remote path is rejected by
+// ConnectionOptions.DenyPaths or AllowPaths
+var ErrPathDenied = errors.New("path denied")
+
+// checkPathPolicy returns ErrPathDenied if remotePath matches a DenyPaths
+// pattern, or if AllowPaths is set and it matches none of them
+// Relative paths are resolved against the working directory first, so
+// patterns should be absolute
+func (c *Connection) checkPathPolicy(remotePath string) error {
+	if len(c.opts.DenyPaths) == 0 && len(c.opts.AllowPaths) == 0 {
+		return nil
+	}
+	resolved := path.Clean(c.resolvePath(remotePath))
+
+	for _, pattern := range c.opts.DenyPaths {
+		matched, err := path.Match(pattern, resolved)
+		if err != nil {
+			return fmt.Errorf("deny pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return fmt.Errorf("%w: %s matches deny pattern %q", ErrPathDenied, resolved, pattern)
+		}
+	}
+
+	if len(c.opts.AllowPaths) == 0 {
+		return nil
+	}
+	for _, pattern := range c.opts.AllowPaths {
+		matched, err := path.Match(pattern, resolved)
+		if err != nil {
+			return fmt.Errorf("allow pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s matches no allow pattern", ErrPathDenied, resolved)
+}
@@ -0,0 +1,137 @@
+package sftp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestConnection_PathPolicy verifies deny patterns win over allow patterns
+// and a set allow list rejects everything it does not match
+func TestConnection_PathPolicy(t *testing.T) {
+	server := NewMockServer(t)
+	server.WriteFile(t, "/data/in.csv", []byte("a,b\n"))
+	server.WriteFile(t, "/data/secret.key", []byte("key"))
+	server.WriteFile(t, "/etc/passwd", []byte("root"))
+
+	opts := server.Options()
+	opts.AllowPaths = []string{"/data", "/data/*"}
+	opts.DenyPaths = []string{"/data/*.key"}
+	conn, err := (&Client{}).ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	localPath := filepath.Join(t.TempDir(), "out")
+
+	t.Run("Allowed paths work", func(t *testing.T) {
+		if err := conn.Upload([]byte("1,2\n"), "/data/out.csv"); err != nil {
+			t.Errorf("Upload failed: %v", err)
+		}
+		if err := conn.Download("/data/in.csv", localPath); err != nil {
+			t.Errorf("Download failed: %v", err)
+		}
+		if _, err := conn.Ls("/data"); err != nil {
+			t.Errorf("Ls failed: %v", err)
+		}
+	})
+
+	t.Run("Deny pattern wins and is named", func(t *testing.T) {
+		err := conn.Download("/data/secret.key", localPath)
+		if !errors.Is(err, ErrPathDenied) || !strings.Contains(err.Error(), `"/data/*.key"`) {
+			t.Errorf("expected ErrPathDenied naming the deny pattern, got: %v", err)
+		}
+		if _, err := conn.RemoveAll("/data/secret.key"); !errors.Is(err, ErrPathDenied) {
+			t.Errorf("expected ErrPathDenied, got: %v", err)
+		}
+		if string(server.ReadFile(t, "/data/secret.key")) != "key" {
+			t.Error("expected the denied file to be kept")
+		}
+	})
+
+	t.Run("Paths outside the allow list are denied", func(t *testing.T) {
+		if err := conn.Upload([]byte("x"), "/etc/passwd"); !errors.Is(err, ErrPathDenied) {
+			t.Errorf("expected ErrPathDenied, got: %v", err)
+		}
+		if _, err := conn.Ls("/etc"); !errors.Is(err, ErrPathDenied) {
+			t.Errorf("expected ErrPathDenied, got: %v", err)
+		}
+		if string(server.ReadFile(t, "/etc/passwd")) != "root" {
+			t.Error("expected the file outside the allow list to be kept")
+		}
+	})
+
+	t.Run("Denied paths below the target refuse the whole operation", func(t *testing.T) {
+		if _, err := conn.RemoveAll("/data"); !errors.Is(err, ErrPathDenied) {
+			t.Errorf("expected ErrPathDenied from RemoveAll, got: %v", err)
+		}
+		if string(server.ReadFile(t, "/data/in.csv")) != "a,b\n" {
+			t.Error("expected nothing to be removed")
+		}
+		if _, err := conn.LsRecursive("/data"); !errors.Is(err, ErrPathDenied) {
+			t.Errorf("expected ErrPathDenied from LsRecursive, got: %v", err)
+		}
+	})
+
+	t.Run("Every entry point applies the policy", func(t *testing.T) {
+		local := filepath.Join(t.TempDir(), "local.txt")
+		if err := os.WriteFile(local, []byte("local"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		chunks := make(chan []byte, 1)
+		chunks <- []byte("x")
+		close(chunks)
+
+		ops := map[string]error{
+			"UploadStream":        conn.UploadStream("/etc/stream", chunks),
+			"UploadWithFlags":     conn.UploadWithFlags([]byte("x"), "/etc/flags", FlagWrite|FlagCreate),
+			"UploadExclusive":     conn.UploadExclusive([]byte("x"), "/etc/exclusive"),
+			"UploadPreserveTimes": conn.UploadPreserveTimes(local, "/etc/times"),
+			"UploadResume":        conn.UploadResume([]byte("rootroot"), "/etc/passwd"),
+		}
+		_, ops["UploadOpenFile"] = conn.UploadOpenFile(f, "/etc/open")
+		_, downloadErrs := conn.DownloadStream("/data/secret.key", 16)
+		ops["DownloadStream"] = <-downloadErrs
+		_, lsErrs := conn.LsChunked("/etc", 16)
+		ops["LsChunked"] = <-lsErrs
+
+		for name, err := range ops {
+			if !errors.Is(err, ErrPathDenied) {
+				t.Errorf("expected ErrPathDenied from %s, got: %v", name, err)
+			}
+		}
+		if string(server.ReadFile(t, "/etc/passwd")) != "root" {
+			t.Error("expected UploadResume to leave the denied file alone")
+		}
+	})
+
+	t.Run("Relative paths are resolved first", func(t *testing.T) {
+		if err := conn.Chdir("/data"); err != nil {
+			t.Fatalf("Chdir failed: %v", err)
+		}
+		defer conn.Chdir("/")
+		if err := conn.Download("../etc/passwd", localPath); !errors.Is(err, ErrPathDenied) {
+			t.Errorf("expected ErrPathDenied, got: %v", err)
+		}
+	})
+
+	t.Run("Invalid pattern returns error", func(t *testing.T) {
+		bad := server.Options()
+		bad.DenyPaths = []string{"/data/["}
+		badConn, err := (&Client{}).ConnectWithOptions(bad)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer badConn.Close()
+		if _, err := badConn.Ls("/data"); err == nil || errors.Is(err, ErrPathDenied) {
+			t.Errorf("expected pattern error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,375 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"go.k6.io/k6/js/modules"
+)
+
+// ErrPoolClosed is returned by Acquire once the pool has been drained
+var ErrPoolClosed = errors.New("pool closed")
+
+// ErrNotAcquired is returned by Release for a connection that is not
+// currently acquired from the pool, e.g. one released twice
+var ErrNotAcquired = errors.New("connection not acquired from this pool")
+
+// exitEvent is event.Exit from k6's internal event package, which
+// extensions cannot import; k6 emits it when the process is about to exit
+const exitEvent = 6
+
+// Pool hands out up to size connections established from the same options
+// Connections are acquired for a unit of work and released back for reuse
+type Pool struct {
+	client *Client
+	opts   ConnectionOptions
+	size   int
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	idle   []*Connection
+	conns  []*Connection // idle plus acquired connections
+	open   int           // conns plus dials in progress
+	dialed int           // connections dialed so far, numbering pool labels
+	errors int           // failed dials
+	closed bool
+	active sync.WaitGroup
+
+	// acquired holds the connections handed out and not yet released
+	acquired map[*Connection]struct{}
+}
+
+// newPool creates an empty pool; connections are dialed by fill and on
+// demand
+func newPool(client *Client, opts ConnectionOptions, size int) *Pool {
+	p := &Pool{
+		client: client,
+		opts:   *opts.Clone(),
+		size:   size,
+
+		acquired: make(map[*Connection]struct{}),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// CreatePool creates a connection pool of at most size connections
+// MinReadyConnections of them are dialed up front, so IsReady can pass in
+// the init stage; the rest are dialed on demand. Returns the first dial
+// error instead of a pool
+// The pool is drained automatically when k6 exits
+func (c *Client) CreatePool(opts ConnectionOptions, size int) (*Pool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("invalid pool size %d", size)
+	}
+
+	p := newPool(c, opts, size)
+	if err := p.fill(); err != nil {
+		_ = p.Drain()
+		return nil, err
+	}
+	if c.module != nil {
+		c.module.registerPool(p)
+	}
+	return p, nil
+}
+
+// fill dials idle connections until the pool has MinReadyConnections of
+// them, or size if that is smaller
+func (p *Pool) fill() error {
+	for range min(p.minReady(), p.size) {
+		p.mu.Lock()
+		p.open++
+		p.mu.Unlock()
+
+		conn, err := p.dial()
+		if err != nil {
+			return err
+		}
+		p.mu.Lock()
+		p.idle = append(p.idle, conn)
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// dial connects a pool connection, labelled in dial order, that the caller
+// has already counted in open
+func (p *Pool) dial() (*Connection, error) {
+	p.mu.Lock()
+	opts := *p.opts.Clone()
+	if opts.Label != "" {
+		opts.Label = fmt.Sprintf("%s-%d", opts.Label, p.dialed)
+	}
+	p.dialed++
+	p.mu.Unlock()
+
+	conn, err := p.client.ConnectWithOptions(opts)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.open--
+		p.errors++
+		p.cond.Signal()
+		if opts.Label != "" {
+			return nil, fmt.Errorf("%s: %w", opts.Label, err)
+		}
+		return nil, err
+	}
+	p.conns = append(p.conns, conn)
+	return conn, nil
+}
+
+// minReady returns MinReadyConnections, defaulted
+func (p *Pool) minReady() int {
+	if p.opts.MinReadyConnections <= 0 {
+		return defaultMinReadyConnections
+	}
+	return p.opts.MinReadyConnections
+}
+
+// Acquire returns an idle connection, dialing a new one while the pool is
+// below its size, or blocks until another caller releases one
+// The connection must be handed back with Release, not closed
+func (p *Pool) Acquire() (*Connection, error) {
+	p.mu.Lock()
+	for !p.closed && len(p.idle) == 0 && p.open >= p.size {
+		p.cond.Wait()
+	}
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+
+	p.active.Add(1)
+	if n := len(p.idle); n > 0 {
+		conn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.acquired[conn] = struct{}{}
+		p.mu.Unlock()
+		return conn, nil
+	}
+	p.open++
+	p.mu.Unlock()
+
+	conn, err := p.dial()
+	if err != nil {
+		p.active.Done()
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.acquired[conn] = struct{}{}
+	p.mu.Unlock()
+	return conn, nil
+}
+
+// Release hands a connection obtained from Acquire back to the pool
+// Returns ErrNotAcquired, leaving the pool unchanged, for a connection
+// that did not come from this pool or was already released
+func (p *Pool) Release(conn *Connection) error {
+	p.mu.Lock()
+	if _, ok := p.acquired[conn]; !ok {
+		p.mu.Unlock()
+		return ErrNotAcquired
+	}
+	delete(p.acquired, conn)
+	defer p.active.Done()
+
+	if p.closed {
+		p.open--
+		p.conns = slices.DeleteFunc(p.conns, func(c *Connection) bool { return c == conn })
+		p.mu.Unlock()
+		return conn.Close()
+	}
+
+	p.idle = append(p.idle, conn)
+	p.cond.Signal()
+	p.mu.Unlock()
+	return nil
+}
+
+// Drain rejects further Acquire calls with ErrPoolClosed, waits for all
+// acquired connections to be released and then closes every connection
+// A drained pool no longer counts for PoolReady or k6's exit
+func (p *Pool) Drain() error {
+	if p.client.module != nil {
+		p.client.module.unregisterPool(p)
+	}
+
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	p.active.Wait()
+
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.conns = nil
+	p.open -= len(idle)
+	p.mu.Unlock()
+
+	var errs []error
+	for _, conn := range idle {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PoolStats is a snapshot of a pool's state
+type PoolStats struct {
+	Size        int                  `json:"size" js:"size"`
+	Active      int                  `json:"active" js:"active"`
+	Idle        int                  `json:"idle" js:"idle"`
+	Errors      int                  `json:"errors" js:"errors"`
+	Closed      bool                 `json:"closed" js:"closed"`
+	Connections []PoolConnectionInfo `json:"connections" js:"connections"`
+}
+
+// PoolConnectionInfo describes one open pool connection
+type PoolConnectionInfo struct {
+	Label string `json:"label" js:"label"`
+	Idle  bool   `json:"idle" js:"idle"`
+}
+
+// Stats returns a snapshot of the pool: acquired and idle connection
+// counts, failed dials and the open connections
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := PoolStats{
+		Size:        p.size,
+		Active:      len(p.conns) - len(p.idle),
+		Idle:        len(p.idle),
+		Errors:      p.errors,
+		Closed:      p.closed,
+		Connections: make([]PoolConnectionInfo, len(p.conns)),
+	}
+	for i, conn := range p.conns {
+		stats.Connections[i] = PoolConnectionInfo{
+			Label: conn.Label(),
+			Idle:  slices.Contains(p.idle, conn),
+		}
+	}
+	return stats
+}
+
+// IsReady reports whether at least MinReadyConnections of the pool's idle
+// connections answer a ping, e.g. for a readiness probe
+// CreatePool dials that many, so a new pool is ready until its connections
+// are acquired or drop
+func (p *Pool) IsReady() bool {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return false
+	}
+	idle := slices.Clone(p.idle)
+	p.mu.Unlock()
+
+	want := p.minReady()
+	ready := 0
+	for _, conn := range idle {
+		if ready == want {
+			break
+		}
+		if conn.ping() == nil {
+			ready++
+		}
+	}
+	return ready >= want
+}
+
+// PoolReady reports whether every pool the VU created and has not drained
+// is ready, see Pool.IsReady. Without any pools it returns false
+func (c *Client) PoolReady() bool {
+	if c.module == nil {
+		return false
+	}
+	return c.module.poolsReady(c)
+}
+
+// registerPool tracks a pool so it is drained when k6 exits
+func (m *Module) registerPool(p *Pool) {
+	m.poolsMu.Lock()
+	defer m.poolsMu.Unlock()
+	m.pools = append(m.pools, p)
+}
+
+// unregisterPool stops tracking a drained pool
+func (m *Module) unregisterPool(p *Pool) {
+	m.poolsMu.Lock()
+	defer m.poolsMu.Unlock()
+	m.pools = slices.DeleteFunc(m.pools, func(pool *Pool) bool { return pool == p })
+}
+
+// poolsReady reports whether client has pools and all of them are ready
+func (m *Module) poolsReady(client *Client) bool {
+	m.poolsMu.Lock()
+	var pools []*Pool
+	for _, p := range m.pools {
+		if p.client == client {
+			pools = append(pools, p)
+		}
+	}
+	m.poolsMu.Unlock()
+
+	if len(pools) == 0 {
+		return false
+	}
+	for _, p := range pools {
+		if !p.IsReady() {
+			return false
+		}
+	}
+	return true
+}
+
+// drainPools drains every pool created through the module
+func (m *Module) drainPools() error {
+	m.poolsMu.Lock()
+	pools := m.pools
+	m.pools = nil
+	m.poolsMu.Unlock()
+
+	var errs []error
+	for _, p := range pools {
+		if err := p.Drain(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// subscribeExit drains all pools, closes the named connections and stops
+// the Prometheus servers once k6 is about to exit, so the process does not
+// leave zombie sessions on the server
+func (m *Module) subscribeExit(vu modules.VU) {
+	if vu == nil || vu.Events().Global == nil {
+		return
+	}
+
+	m.exitOnce.Do(func() {
+		events := vu.Events().Global
+		subID, ch := events.Subscribe(exitEvent)
+		go func() {
+			e, ok := <-ch
+			if !ok {
+				return
+			}
+			_ = m.drainPools()
+			_ = m.closeNamedConnections()
+			m.stopExporters()
+			e.Done()
+			events.Unsubscribe(subID)
+		}()
+	})
+}
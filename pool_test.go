@@ -0,0 +1,280 @@
+package sftp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPool_AcquireRelease verifies connections are dialed on demand, reused
+// after release and capped at the pool size
+func TestPool_AcquireRelease(t *testing.T) {
+	server := NewMockServer(t)
+	m := &Module{}
+	c := m.NewModuleInstance(nil).(*Client)
+
+	t.Run("Invalid size returns error", func(t *testing.T) {
+		if _, err := c.CreatePool(server.Options(), 0); err == nil {
+			t.Error("expected error for pool size 0, got nil")
+		}
+	})
+
+	pool, err := c.CreatePool(server.Options(), 1)
+	if err != nil {
+		t.Fatalf("CreatePool failed: %v", err)
+	}
+	t.Cleanup(func() { pool.Drain() })
+
+	t.Run("Released connection is reused", func(t *testing.T) {
+		first, err := pool.Acquire()
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		if err := pool.Release(first); err != nil {
+			t.Fatalf("Release failed: %v", err)
+		}
+
+		second, err := pool.Acquire()
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		defer pool.Release(second)
+		if second != first {
+			t.Error("expected the released connection to be reused")
+		}
+	})
+
+	t.Run("Acquire blocks while the pool is exhausted", func(t *testing.T) {
+		conn, err := pool.Acquire()
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+
+		acquired := make(chan *Connection)
+		go func() {
+			next, _ := pool.Acquire()
+			acquired <- next
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("expected Acquire to block until a release")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		pool.Release(conn)
+		select {
+		case next := <-acquired:
+			pool.Release(next)
+		case <-time.After(time.Second):
+			t.Fatal("expected Acquire to return after release")
+		}
+	})
+	t.Run("Releasing twice returns ErrNotAcquired", func(t *testing.T) {
+		conn, err := pool.Acquire()
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		if err := pool.Release(conn); err != nil {
+			t.Fatalf("Release failed: %v", err)
+		}
+		if err := pool.Release(conn); !errors.Is(err, ErrNotAcquired) {
+			t.Errorf("expected ErrNotAcquired, got: %v", err)
+		}
+		if stats := pool.Stats(); stats.Idle != 1 || stats.Active != 0 {
+			t.Errorf("expected one idle connection, got %+v", stats)
+		}
+	})
+
+	t.Run("Foreign connection returns ErrNotAcquired", func(t *testing.T) {
+		if err := pool.Release(server.Connect(t)); !errors.Is(err, ErrNotAcquired) {
+			t.Errorf("expected ErrNotAcquired, got: %v", err)
+		}
+		if stats := pool.Stats(); len(stats.Connections) != 1 || stats.Idle != 1 {
+			t.Errorf("expected the foreign connection not to join the pool, got %+v", stats)
+		}
+	})
+}
+
+// TestPool_Drain verifies Drain waits for acquired connections, closes them
+// and rejects further Acquire calls
+func TestPool_Drain(t *testing.T) {
+	server := NewMockServer(t)
+	m := &Module{}
+	c := m.NewModuleInstance(nil).(*Client)
+
+	pool, err := c.CreatePool(server.Options(), 2)
+	if err != nil {
+		t.Fatalf("CreatePool failed: %v", err)
+	}
+
+	idle, err := pool.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	pool.Release(idle)
+
+	active, err := pool.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	drained := make(chan error)
+	go func() { drained <- m.drainPools() }()
+
+	select {
+	case <-drained:
+		t.Fatal("expected Drain to wait for the active connection")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := pool.Release(active); err != nil {
+		t.Errorf("Release during drain failed: %v", err)
+	}
+
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Errorf("Drain failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Drain to return after release")
+	}
+
+	if idle.client() != nil || active.client() != nil {
+		t.Error("expected all connections to be closed after Drain")
+	}
+	if _, err := pool.Acquire(); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("expected ErrPoolClosed after Drain, got: %v", err)
+	}
+}
+
+// TestPool_Labels verifies pool connections are labelled in dial order and
+// dial errors name the failing connection
+func TestPool_Labels(t *testing.T) {
+	server := NewMockServer(t)
+	c := (&Module{}).NewModuleInstance(nil).(*Client)
+
+	t.Run("Connections are labelled with their index", func(t *testing.T) {
+		opts := server.Options()
+		opts.Label = "worker"
+		pool, err := c.CreatePool(opts, 2)
+		if err != nil {
+			t.Fatalf("CreatePool failed: %v", err)
+		}
+		defer pool.Drain()
+
+		first, err := pool.Acquire()
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		defer pool.Release(first)
+		second, err := pool.Acquire()
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		defer pool.Release(second)
+
+		if first.Label() != "worker-0" || second.Label() != "worker-1" {
+			t.Errorf("expected labels worker-0 and worker-1, got %q and %q", first.Label(), second.Label())
+		}
+	})
+
+	t.Run("Dial error includes the label", func(t *testing.T) {
+		opts := server.Options()
+		opts.Label = "worker"
+		opts.Password = "wrong"
+		pool, err := c.CreatePool(opts, 1)
+		if err == nil || !strings.HasPrefix(err.Error(), "worker-0: ") {
+			t.Errorf("expected error prefixed with worker-0, got: %v", err)
+		}
+		if pool != nil {
+			t.Error("expected no pool when a connection cannot be dialed")
+		}
+	})
+}
+
+// TestPool_IsReady verifies a pool is ready while enough idle connections
+// answer a ping
+func TestPool_IsReady(t *testing.T) {
+	server := NewMockServer(t)
+	m := &Module{}
+	c := m.NewModuleInstance(nil).(*Client)
+
+	t.Run("Module without pools is not ready", func(t *testing.T) {
+		if c.PoolReady() {
+			t.Error("expected poolReady to be false without pools")
+		}
+	})
+
+	opts := server.Options()
+	opts.MinReadyConnections = 2
+	pool, err := c.CreatePool(opts, 2)
+	if err != nil {
+		t.Fatalf("CreatePool failed: %v", err)
+	}
+	t.Cleanup(func() { pool.Drain() })
+
+	t.Run("New pool is ready", func(t *testing.T) {
+		if !pool.IsReady() || !c.PoolReady() {
+			t.Error("expected the pre-dialed connections to make the pool ready")
+		}
+		if stats := pool.Stats(); stats.Idle != 2 {
+			t.Errorf("expected 2 idle connections, got %+v", stats)
+		}
+	})
+
+	t.Run("Pool is ready with enough idle connections", func(t *testing.T) {
+		first, err := pool.Acquire()
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		second, err := pool.Acquire()
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		if pool.IsReady() {
+			t.Error("expected pool without idle connections not to be ready")
+		}
+		pool.Release(first)
+		if pool.IsReady() {
+			t.Error("expected pool with one idle connection not to be ready")
+		}
+
+		pool.Release(second)
+		if !pool.IsReady() || !c.PoolReady() {
+			t.Error("expected pool with two idle connections to be ready")
+		}
+	})
+
+	t.Run("Other VUs' pools do not count", func(t *testing.T) {
+		other := m.NewModuleInstance(nil).(*Client)
+		if other.PoolReady() {
+			t.Error("expected poolReady to be false for a VU without pools")
+		}
+	})
+
+	t.Run("Drained pools are unregistered", func(t *testing.T) {
+		extra, err := c.CreatePool(server.Options(), 1)
+		if err != nil {
+			t.Fatalf("CreatePool failed: %v", err)
+		}
+		if err := extra.Drain(); err != nil {
+			t.Fatalf("Drain failed: %v", err)
+		}
+		if !c.PoolReady() {
+			t.Error("expected the drained pool not to count")
+		}
+		if len(m.pools) != 1 {
+			t.Errorf("expected 1 registered pool, got %d", len(m.pools))
+		}
+	})
+
+	t.Run("Dropped connections fail the ping", func(t *testing.T) {
+		server.Close()
+		if pool.IsReady() {
+			t.Error("expected pool to be unready after the server closed")
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// prometheusMetrics mirrors the extension's custom metrics in a Prometheus
+// registry served by ExportPrometheus
+// The k6 registry only describes metrics, samples go straight to the
+// outputs, so values are recorded here separately
+type prometheusMetrics struct {
+	registry         *prometheus.Registry
+	transferProgress *prometheus.GaugeVec
+	// exporters counts the running ExportPrometheus servers; nothing is
+	// recorded while it is zero, so per-path series do not pile up unread
+	exporters atomic.Int32
+}
+
+func newPrometheusMetrics() *prometheusMetrics {
+	p := &prometheusMetrics{
+		registry: prometheus.NewRegistry(),
+		transferProgress: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "sftp_transfer_progress_bytes",
+			Help: "Bytes transferred so far by the current upload or download",
+		}, []string{"remote_path"}),
+	}
+	p.registry.MustRegister(p.transferProgress)
+	return p
+}
+
+// active reports whether an exporter is running
+func (p *prometheusMetrics) active() bool {
+	return p != nil && p.exporters.Load() > 0
+}
+
+// prometheus returns the module's Prometheus metrics, creating them on
+// first use
+func (m *Module) prometheus() *prometheusMetrics {
+	m.prometheusOnce.Do(func() {
+		m.prometheusMetrics = newPrometheusMetrics()
+	})
+	return m.prometheusMetrics
+}
+
+// ExportPrometheus serves the extension's custom metrics in the Prometheus
+// text format at http://listenAddr/metrics, for deployments that scrape
+// Prometheus rather than use a k6 output. Calling stop shuts the server
+// down; it is safe to call more than once
+// k6 runs init code once per VU, so calls for a listenAddr already being
+// served return that server's stop instead of listening again. Servers
+// still running when k6 exits are shut down then
+// Only transfers with ConnectionOptions.ProgressMetrics set are recorded,
+// the same as for the k6 metric
+func (m *Module) ExportPrometheus(listenAddr string) (stop func(), err error) {
+	m.exportersMu.Lock()
+	defer m.exportersMu.Unlock()
+	if stop, ok := m.exporters[listenAddr]; ok {
+		return stop, nil
+	}
+
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", listenAddr, err)
+	}
+
+	metrics := m.prometheus()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	metrics.exporters.Add(1)
+	go func() { _ = server.Serve(listener) }()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			m.exportersMu.Lock()
+			delete(m.exporters, listenAddr)
+			m.exportersMu.Unlock()
+			metrics.exporters.Add(-1)
+			_ = server.Close()
+		})
+	}
+	if m.exporters == nil {
+		m.exporters = make(map[string]func())
+	}
+	m.exporters[listenAddr] = stop
+	return stop, nil
+}
+
+// stopExporters shuts down every server started by ExportPrometheus
+func (m *Module) stopExporters() {
+	m.exportersMu.Lock()
+	stops := make([]func(), 0, len(m.exporters))
+	for _, stop := range m.exporters {
+		stops = append(stops, stop)
+	}
+	m.exportersMu.Unlock()
+
+	for _, stop := range stops {
+		stop()
+	}
+}
+
+// ExportPrometheus starts a Prometheus endpoint for the whole module, see
+// Module.ExportPrometheus
+func (c *Client) ExportPrometheus(listenAddr string) (func(), error) {
+	if c.module == nil {
+		return nil, errors.New("client is not bound to a module")
+	}
+	return c.module.ExportPrometheus(listenAddr)
+}
@@ -0,0 +1,120 @@
+package sftp
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestModule_ExportPrometheus verifies transfer progress is served in the
+// Prometheus text format while an exporter runs
+func TestModule_ExportPrometheus(t *testing.T) {
+	server := NewMockServer(t)
+	module := &Module{}
+	client, ok := module.NewModuleInstance(nil).(*Client)
+	if !ok {
+		t.Fatal("expected instance to be *Client")
+	}
+
+	opts := server.Options()
+	opts.ProgressMetrics = true
+	conn, err := client.ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// Reserve a free port for the exporter
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	scrape := func(t *testing.T) string {
+		t.Helper()
+		resp, err := http.Get("http://" + addr + "/metrics")
+		if err != nil {
+			t.Fatalf("scrape failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// Nothing is recorded until an exporter runs
+	if err := conn.Upload([]byte("early"), "/early.bin"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	stop, err := client.ExportPrometheus(addr)
+	if err != nil {
+		t.Fatalf("ExportPrometheus failed: %v", err)
+	}
+	t.Cleanup(stop)
+
+	t.Run("Progress is exported", func(t *testing.T) {
+		if err := conn.Upload(bytes.Repeat([]byte("x"), 40*1024), "/big.bin"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		body := scrape(t)
+		if !strings.Contains(body, `sftp_transfer_progress_bytes{remote_path="/big.bin"} 40960`) {
+			t.Errorf("expected progress for /big.bin, got:\n%s", body)
+		}
+		if strings.Contains(body, "/early.bin") {
+			t.Error("expected no series for a transfer before exporting")
+		}
+	})
+
+	t.Run("Later calls for the address return the running server", func(t *testing.T) {
+		other := module.NewModuleInstance(nil).(*Client)
+		if _, err := other.ExportPrometheus(addr); err != nil {
+			t.Fatalf("expected the running server, got: %v", err)
+		}
+		if !strings.Contains(scrape(t), "sftp_transfer_progress_bytes") {
+			t.Error("expected the server to keep serving")
+		}
+	})
+
+	t.Run("Address in use returns error", func(t *testing.T) {
+		busy, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		defer busy.Close()
+		if _, err := module.ExportPrometheus(busy.Addr().String()); err == nil {
+			t.Error("expected error for an address in use")
+		}
+	})
+
+	t.Run("Stop shuts the server down", func(t *testing.T) {
+		stop()
+		stop()
+		if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+			t.Error("expected scrape to fail after stop")
+		}
+	})
+
+	t.Run("Exit stops the remaining servers", func(t *testing.T) {
+		if _, err := module.ExportPrometheus(addr); err != nil {
+			t.Fatalf("ExportPrometheus failed: %v", err)
+		}
+		module.stopExporters()
+		if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+			t.Error("expected scrape to fail after exit")
+		}
+		if len(module.exporters) != 0 {
+			t.Errorf("expected no registered servers, got %d", len(module.exporters))
+		}
+	})
+
+	t.Run("ExportPrometheus returns error without a module", func(t *testing.T) {
+		if _, err := (&Client{}).ExportPrometheus(addr); err == nil {
+			t.Error("expected error without a module")
+		}
+	})
+}
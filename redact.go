@@ -0,0 +1,148 @@
+package sftp
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// redacted replaces sensitive option values in RedactedString
+const redacted = "***"
+
+// optionsJSON is the JSON form of ConnectionOptions, without credentials
+type optionsJSON struct {
+	Host                       string   `json:"host"`
+	Port                       int      `json:"port"`
+	Username                   string   `json:"username"`
+	TLSClientCert              string   `json:"tlsClientCert,omitempty"`
+	TLSCAFile                  string   `json:"tlsCAFile,omitempty"`
+	TLSServerName              string   `json:"tlsServerName,omitempty"`
+	TLSInsecureSkipVerify      bool     `json:"tlsInsecureSkipVerify,omitempty"`
+	RekeyThreshold             uint64   `json:"rekeyThreshold,omitempty"`
+	WebSocketURL               string   `json:"webSocketURL,omitempty"`
+	HostKeyAlgorithms          []string `json:"hostKeyAlgorithms,omitempty"`
+	PreferredHostKeyAlgorithms []string `json:"preferredHostKeyAlgorithms,omitempty"`
+	KnownHostsFile             string   `json:"knownHostsFile,omitempty"`
+	MinRSAKeyBits              int      `json:"minRSAKeyBits,omitempty"`
+	JSONMode                   bool     `json:"jsonMode"`
+	Label                      string   `json:"label,omitempty"`
+	MinReadyConnections        int      `json:"minReadyConnections,omitempty"`
+	MaxGrepResults             int      `json:"maxGrepResults,omitempty"`
+	MaxLsFiles                 int      `json:"maxLsFiles,omitempty"`
+	PollInterval               string   `json:"pollInterval,omitempty"`
+	LockTTL                    string   `json:"lockTTL,omitempty"`
+	OperationTimeout           string   `json:"operationTimeout,omitempty"`
+	TCPConnectTimeout          string   `json:"tcpConnectTimeout,omitempty"`
+	SSHAuthTimeout             string   `json:"sshAuthTimeout,omitempty"`
+	SFTPInitTimeout            string   `json:"sftpInitTimeout,omitempty"`
+	ProgressMetrics            bool     `json:"progressMetrics"`
+	DisableConcurrentReads     bool     `json:"disableConcurrentReads"`
+	DisableConcurrentWrites    bool     `json:"disableConcurrentWrites"`
+	Debug                      bool     `json:"debug"`
+	CheckWritePermission       bool     `json:"checkWritePermission"`
+	RemoteIdentity             string   `json:"remoteIdentity,omitempty"`
+	AllowedUIDs                []int    `json:"allowedUIDs,omitempty"`
+	AllowedGIDs                []int    `json:"allowedGIDs,omitempty"`
+	DenyPaths                  []string `json:"denyPaths,omitempty"`
+	AllowPaths                 []string `json:"allowPaths,omitempty"`
+}
+
+// MarshalJSON encodes the options for debugging, leaving out Password,
+// PrivateKey, Passphrase and TLSClientKey, the password of a WebSocketURL,
+// and the Go-only AuthMethods and hooks. Durations are encoded as strings
+// such as "500ms"
+func (opts ConnectionOptions) MarshalJSON() ([]byte, error) {
+	out := optionsJSON{
+		Host:                       opts.Host,
+		Port:                       opts.Port,
+		Username:                   opts.Username,
+		TLSClientCert:              opts.TLSClientCert,
+		TLSCAFile:                  opts.TLSCAFile,
+		TLSServerName:              opts.TLSServerName,
+		TLSInsecureSkipVerify:      opts.TLSInsecureSkipVerify,
+		RekeyThreshold:             opts.RekeyThreshold,
+		WebSocketURL:               redactURL(opts.WebSocketURL),
+		HostKeyAlgorithms:          opts.HostKeyAlgorithms,
+		PreferredHostKeyAlgorithms: opts.PreferredHostKeyAlgorithms,
+		KnownHostsFile:             opts.KnownHostsFile,
+		MinRSAKeyBits:              opts.MinRSAKeyBits,
+		JSONMode:                   opts.JSONMode,
+		Label:                      opts.Label,
+		MinReadyConnections:        opts.MinReadyConnections,
+		MaxGrepResults:             opts.MaxGrepResults,
+		MaxLsFiles:                 opts.MaxLsFiles,
+		ProgressMetrics:            opts.ProgressMetrics,
+		DisableConcurrentReads:     opts.DisableConcurrentReads,
+		DisableConcurrentWrites:    opts.DisableConcurrentWrites,
+		Debug:                      opts.Debug,
+		CheckWritePermission:       opts.CheckWritePermission,
+		RemoteIdentity:             opts.RemoteIdentity,
+		AllowedUIDs:                opts.AllowedUIDs,
+		AllowedGIDs:                opts.AllowedGIDs,
+		DenyPaths:                  opts.DenyPaths,
+		AllowPaths:                 opts.AllowPaths,
+	}
+	if opts.PollInterval > 0 {
+		out.PollInterval = opts.PollInterval.String()
+	}
+	if opts.LockTTL > 0 {
+		out.LockTTL = opts.LockTTL.String()
+	}
+	if opts.OperationTimeout > 0 {
+		out.OperationTimeout = opts.OperationTimeout.String()
+	}
+	if opts.TCPConnectTimeout > 0 {
+		out.TCPConnectTimeout = opts.TCPConnectTimeout.String()
+	}
+	if opts.SSHAuthTimeout > 0 {
+		out.SSHAuthTimeout = opts.SSHAuthTimeout.String()
+	}
+	if opts.SFTPInitTimeout > 0 {
+		out.SFTPInitTimeout = opts.SFTPInitTimeout.String()
+	}
+	return json.Marshal(out)
+}
+
+// RedactedString summarises the options as user@host:port followed by the
+// label, WebSocket URL and credentials that are set, each credential shown
+// as "***". Use it wherever options end up in logs or error messages
+func (opts ConnectionOptions) RedactedString() string {
+	var b strings.Builder
+	b.WriteString(opts.Username)
+	b.WriteString("@")
+	b.WriteString(net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port)))
+
+	field := func(name, value string) {
+		if value != "" {
+			b.WriteString(" " + name + "=" + value)
+		}
+	}
+	secret := func(name, value string) {
+		if value != "" {
+			field(name, redacted)
+		}
+	}
+
+	field("label", opts.Label)
+	field("webSocketURL", redactURL(opts.WebSocketURL))
+	secret("password", opts.Password)
+	secret("privateKey", opts.PrivateKey)
+	secret("passphrase", opts.Passphrase)
+	secret("tlsClientKey", opts.TLSClientKey)
+	if len(opts.AuthMethods) > 0 {
+		field("authMethods", strconv.Itoa(len(opts.AuthMethods)))
+	}
+	return b.String()
+}
+
+// redactURL masks the password of a URL's user info, if any, the way
+// url.URL.Redacted does. A URL that does not parse is masked entirely
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,114 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// NamedConnect returns the connection registered under name, establishing
+// and registering it first if none exists
+// A name already registered for a different host, port, username or
+// password is an error rather than the other connection
+// Named connections live on the Module and are shared by all VUs, so
+// scenarios can reuse one pre-established connection by name, e.g. one
+// made in setup(). The connection is not bound to any VU: its operations
+// are not cancelled with an iteration and it emits no log lines or metric
+// samples of its own. Named connections still open when k6 exits are
+// closed then
+func (c *Client) NamedConnect(name, host, username, password string, port int) (*Connection, error) {
+	connections, err := c.namedConnections()
+	if err != nil {
+		return nil, err
+	}
+	if conn, ok := connections.Load(name); ok {
+		return conn.(*Connection).checkNamed(name, host, username, password, port)
+	}
+
+	conn, err := c.detached().Connect(host, username, password, port)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another VU may have registered the name while we were connecting
+	if existing, loaded := connections.LoadOrStore(name, conn); loaded {
+		conn.Close()
+		return existing.(*Connection).checkNamed(name, host, username, password, port)
+	}
+
+	return conn, nil
+}
+
+// checkNamed returns c if it was connected with the given parameters, or
+// an error naming the first that differs
+func (c *Connection) checkNamed(name, host, username, password string, port int) (*Connection, error) {
+	if port == 0 {
+		port = defaultPort
+	}
+	switch {
+	case c.opts.Host != host:
+		return nil, fmt.Errorf("connection %q is registered for host %q, not %q", name, c.opts.Host, host)
+	case c.opts.Port != port:
+		return nil, fmt.Errorf("connection %q is registered for port %d, not %d", name, c.opts.Port, port)
+	case c.opts.Username != username:
+		return nil, fmt.Errorf("connection %q is registered for user %q, not %q", name, c.opts.Username, username)
+	case c.opts.Password != password:
+		return nil, fmt.Errorf("connection %q is registered with a different password", name)
+	}
+	return c, nil
+}
+
+// NamedGet returns the connection registered under name
+func (c *Client) NamedGet(name string) (*Connection, error) {
+	connections, err := c.namedConnections()
+	if err != nil {
+		return nil, err
+	}
+	conn, ok := connections.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("no connection named %q", name)
+	}
+	return conn.(*Connection), nil
+}
+
+// NamedClose closes the connection registered under name and removes it
+// from the registry
+func (c *Client) NamedClose(name string) error {
+	connections, err := c.namedConnections()
+	if err != nil {
+		return err
+	}
+	conn, ok := connections.LoadAndDelete(name)
+	if !ok {
+		return fmt.Errorf("no connection named %q", name)
+	}
+	return conn.(*Connection).Close()
+}
+
+// detached returns a Client of the same Module bound to no VU, for
+// connections outliving the VU that creates them
+func (c *Client) detached() *Client {
+	return &Client{module: c.module, metrics: c.metrics}
+}
+
+// namedConnections returns the Module's registry of named connections
+func (c *Client) namedConnections() (*sync.Map, error) {
+	if c.module == nil {
+		return nil, errors.New("client is not bound to a module")
+	}
+	return &c.module.connections, nil
+}
+
+// closeNamedConnections closes and unregisters every named connection
+func (m *Module) closeNamedConnections() error {
+	var errs []error
+	m.connections.Range(func(name, _ interface{}) bool {
+		if conn, ok := m.connections.LoadAndDelete(name); ok {
+			if err := conn.(*Connection).Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close connection %q: %w", name, err))
+			}
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
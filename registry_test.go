@@ -0,0 +1,174 @@
+package sftp
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+// TestRegistry_NamedConnections verifies named connections are shared by all
+// Clients of a Module
+func TestRegistry_NamedConnections(t *testing.T) {
+	server := NewMockServer(t)
+	m := &Module{}
+	vu1 := m.NewModuleInstance(nil).(*Client)
+	vu2 := m.NewModuleInstance(nil).(*Client)
+
+	t.Run("NamedGet on unknown name returns error", func(t *testing.T) {
+		conn, err := vu1.NamedGet("missing")
+		if err == nil {
+			t.Error("expected error for unknown name, got nil")
+		}
+		if conn != nil {
+			t.Error("expected nil connection for unknown name")
+		}
+	})
+
+	t.Run("NamedConnect registers a connection visible to other VUs", func(t *testing.T) {
+		conn, err := vu1.NamedConnect("shared", server.Host, server.User, server.Password, server.Port)
+		if err != nil {
+			t.Fatalf("NamedConnect failed: %v", err)
+		}
+
+		got, err := vu2.NamedGet("shared")
+		if err != nil {
+			t.Fatalf("NamedGet failed: %v", err)
+		}
+		if got != conn {
+			t.Error("expected both VUs to share the same connection")
+		}
+	})
+
+	t.Run("NamedConnect reuses an existing connection", func(t *testing.T) {
+		first, _ := vu1.NamedGet("shared")
+		before := server.ConnCount()
+		conn, err := vu2.NamedConnect("shared", server.Host, server.User, server.Password, server.Port)
+		if err != nil {
+			t.Fatalf("NamedConnect failed: %v", err)
+		}
+		if conn != first {
+			t.Error("expected the registered connection to be returned")
+		}
+		if got := server.ConnCount(); got != before {
+			t.Errorf("expected no new TCP connection, got %d more", got-before)
+		}
+	})
+
+	t.Run("NamedConnect with different parameters returns error", func(t *testing.T) {
+		for _, tc := range []struct {
+			user, password string
+			port           int
+		}{
+			{"nobody", server.Password, server.Port},
+			{server.User, "wrong", server.Port},
+			{server.User, server.Password, server.Port + 1},
+		} {
+			if conn, err := vu2.NamedConnect("shared", server.Host, tc.user, tc.password, tc.port); err == nil || conn != nil {
+				t.Errorf("expected error for %s@:%d, got %v (err=%v)", tc.user, tc.port, conn, err)
+			}
+		}
+		if _, err := vu2.NamedConnect("shared", "other.example", server.User, server.Password, server.Port); err == nil {
+			t.Error("expected error for a different host")
+		}
+	})
+
+	t.Run("NamedClose closes and unregisters the connection", func(t *testing.T) {
+		conn, _ := vu1.NamedGet("shared")
+		if err := vu2.NamedClose("shared"); err != nil {
+			t.Fatalf("NamedClose failed: %v", err)
+		}
+		if conn.client() != nil {
+			t.Error("expected connection to be closed")
+		}
+		if _, err := vu1.NamedGet("shared"); err == nil {
+			t.Error("expected name to be unregistered after NamedClose")
+		}
+		if err := vu1.NamedClose("shared"); err == nil {
+			t.Error("expected error closing an unknown name")
+		}
+	})
+
+	t.Run("Concurrent NamedConnect registers a single connection", func(t *testing.T) {
+		numGoroutines := 10
+		conns := make([]*Connection, numGoroutines)
+
+		var wg sync.WaitGroup
+		wg.Add(numGoroutines)
+		for i := 0; i < numGoroutines; i++ {
+			go func(id int) {
+				defer wg.Done()
+				c := m.NewModuleInstance(nil).(*Client)
+				conn, err := c.NamedConnect("race", server.Host, server.User, server.Password, server.Port)
+				if err != nil {
+					t.Errorf("goroutine %d: NamedConnect failed: %v", id, err)
+					return
+				}
+				conns[id] = conn
+			}(i)
+		}
+		wg.Wait()
+
+		for i, conn := range conns {
+			if conn != conns[0] {
+				t.Errorf("goroutine %d got a different connection", i)
+			}
+		}
+		if err := vu1.NamedClose("race"); err != nil {
+			t.Errorf("NamedClose failed: %v", err)
+		}
+	})
+
+	t.Run("Connections outlive the creating VU's context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		creator := m.NewModuleInstance(&modulestest.VU{CtxField: ctx}).(*Client)
+		user := m.NewModuleInstance(&modulestest.VU{CtxField: context.Background()}).(*Client)
+
+		if _, err := creator.NamedConnect("setup", server.Host, server.User, server.Password, server.Port); err != nil {
+			t.Fatalf("NamedConnect failed: %v", err)
+		}
+		// k6 cancels the VU context at the end of every iteration
+		cancel()
+
+		conn, err := user.NamedGet("setup")
+		if err != nil {
+			t.Fatalf("NamedGet failed: %v", err)
+		}
+		if _, err := conn.Ls("/"); err != nil {
+			t.Errorf("expected the connection to work from another VU, got: %v", err)
+		}
+		if err := user.NamedClose("setup"); err != nil {
+			t.Errorf("NamedClose failed: %v", err)
+		}
+	})
+
+	t.Run("Exit closes the remaining connections", func(t *testing.T) {
+		conn, err := vu1.NamedConnect("leftover", server.Host, server.User, server.Password, server.Port)
+		if err != nil {
+			t.Fatalf("NamedConnect failed: %v", err)
+		}
+		if err := m.closeNamedConnections(); err != nil {
+			t.Fatalf("closeNamedConnections failed: %v", err)
+		}
+		if conn.client() != nil {
+			t.Error("expected connection to be closed")
+		}
+		if _, err := vu1.NamedGet("leftover"); err == nil {
+			t.Error("expected name to be unregistered")
+		}
+	})
+
+	t.Run("Client without a module returns error", func(t *testing.T) {
+		c := &Client{}
+		if _, err := c.NamedConnect("orphan", server.Host, server.User, server.Password, server.Port); err == nil {
+			t.Error("expected NamedConnect error without a module")
+		}
+		if _, err := c.NamedGet("orphan"); err == nil {
+			t.Error("expected NamedGet error without a module")
+		}
+		if err := c.NamedClose("orphan"); err == nil {
+			t.Error("expected NamedClose error without a module")
+		}
+	})
+}
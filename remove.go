@@ -0,0 +1,71 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// RemoveAll deletes remotePath and, if it is a directory, everything below
+// it, returning the number of files and directories deleted, remotePath
+// included. Symlinks are removed, not followed
+// Stops at the first failure, returning the count deleted so far, and
+// ErrRemoteNotFound if remotePath does not exist. Nothing is deleted if the
+// path policy denies remotePath or anything below it
+func (c *Connection) RemoveAll(remotePath string) (_ int, err error) {
+	defer c.observe("removeAll", remotePath, time.Now(), &err)
+	return withTimeout(c, func(ctx context.Context) (int, error) { return c.removeAll(ctx, remotePath) })
+}
+
+func (c *Connection) removeAll(ctx context.Context, remotePath string) (int, error) {
+	if c.client() == nil {
+		return 0, errors.New("not connected")
+	}
+	if err := c.checkPathPolicy(remotePath); err != nil {
+		return 0, err
+	}
+	root := c.resolvePath(remotePath)
+
+	type node struct {
+		path  string
+		isDir bool
+	}
+	var nodes []node
+	walker := c.client().Walk(root)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if err := walker.Err(); err != nil {
+			if walker.Path() == root && errors.Is(err, os.ErrNotExist) {
+				return 0, fmt.Errorf("%w: %s", ErrRemoteNotFound, remotePath)
+			}
+			return 0, fmt.Errorf("walk %s: %w", walker.Path(), err)
+		}
+		if err := c.checkPathPolicy(walker.Path()); err != nil {
+			return 0, err
+		}
+		nodes = append(nodes, node{path: walker.Path(), isDir: walker.Stat().IsDir()})
+	}
+
+	// Walk lists a directory before its contents, so deleting in reverse
+	// empties every directory before it is removed
+	removed := 0
+	for _, n := range slices.Backward(nodes) {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		remove := c.client().Remove
+		if n.isDir {
+			remove = c.client().RemoveDirectory
+		}
+		if err := remove(n.path); err != nil {
+			return removed, fmt.Errorf("remove %s: %w", n.path, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,71 @@
+package sftp
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestConnection_RemoveAll verifies a tree is deleted bottom-up and every
+// deleted file and directory is counted
+func TestConnection_RemoveAll(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("Tree is deleted and counted", func(t *testing.T) {
+		server.WriteFile(t, "/cleanup/a.txt", []byte("a"))
+		server.WriteFile(t, "/cleanup/sub/b.txt", []byte("b"))
+		server.WriteFile(t, "/cleanup/sub/deeper/c.txt", []byte("c"))
+		if err := os.Mkdir(server.localPath("/cleanup/empty"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+
+		n, err := conn.RemoveAll("/cleanup")
+		if err != nil {
+			t.Fatalf("RemoveAll failed: %v", err)
+		}
+		// 3 files plus cleanup, sub, deeper and empty
+		if n != 7 {
+			t.Errorf("expected 7 deleted entries, got %d", n)
+		}
+		if _, err := os.Stat(server.localPath("/cleanup")); !os.IsNotExist(err) {
+			t.Errorf("expected tree to be gone, got: %v", err)
+		}
+	})
+
+	t.Run("Single file counts once", func(t *testing.T) {
+		server.WriteFile(t, "/single.txt", []byte("x"))
+		n, err := conn.RemoveAll("/single.txt")
+		if err != nil || n != 1 {
+			t.Errorf("expected 1 deleted entry, got %d (err=%v)", n, err)
+		}
+	})
+
+	t.Run("Failure returns count so far", func(t *testing.T) {
+		server.WriteFile(t, "/partial/a.txt", []byte("a"))
+		server.WriteFile(t, "/partial/b.txt", []byte("b"))
+		// pkg/sftp retries a failed Remove as Rmdir, so fail both
+		server.FailCmds("/partial/a.txt", 2)
+
+		n, err := conn.RemoveAll("/partial")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if n != 1 {
+			t.Errorf("expected 1 deleted entry before the failure, got %d", n)
+		}
+	})
+
+	t.Run("Missing path returns ErrRemoteNotFound", func(t *testing.T) {
+		if _, err := conn.RemoveAll("/missing"); !errors.Is(err, ErrRemoteNotFound) {
+			t.Errorf("expected ErrRemoteNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("RemoveAll returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).RemoveAll("/cleanup")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
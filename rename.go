@@ -0,0 +1,117 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// renameMatch is the data toTemplate is rendered with in RenamePattern
+type renameMatch struct {
+	// Match is the text fromPattern matched in the file name
+	Match string
+	// Groups holds the capture groups, the first at index 0
+	Groups []string
+}
+
+// RenamePattern renames every file in remoteDirPath whose name matches the
+// Go regular expression fromPattern to the name rendered from toTemplate, a
+// text/template given the match as .Match and the capture groups as
+// .Groups, e.g. `report-(\d+)\.csv` with `{{index .Groups 0}}.csv`
+// Returns "old -> new" for each renamed file, in name order. All names are
+// rendered and checked for collisions, with each other and with existing
+// entries, before anything is renamed; a failed rename stops the batch,
+// leaving earlier renames in place
+func (c *Connection) RenamePattern(remoteDirPath, fromPattern, toTemplate string) (_ []string, err error) {
+	defer c.observe("renamePattern", remoteDirPath, time.Now(), &err)
+	return withTimeout(c, func(ctx context.Context) ([]string, error) {
+		return c.renamePattern(ctx, remoteDirPath, fromPattern, toTemplate)
+	})
+}
+
+func (c *Connection) renamePattern(ctx context.Context, remoteDirPath, fromPattern, toTemplate string) ([]string, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+	remoteDirPath = c.resolvePath(remoteDirPath)
+
+	re, err := regexp.Compile(fromPattern)
+	if err != nil {
+		return nil, fmt.Errorf("parse pattern: %w", err)
+	}
+	tmpl, err := template.New("rename").Option("missingkey=error").Parse(toTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	entries, err := c.client().ReadDir(remoteDirPath)
+	if err != nil {
+		return nil, fmt.Errorf("read directory: %w", err)
+	}
+
+	slices.SortFunc(entries, func(a, b os.FileInfo) int { return strings.Compare(a.Name(), b.Name()) })
+	existing := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		existing[entry.Name()] = true
+	}
+
+	type rename struct{ from, to string }
+	var renames []rename
+	targets := map[string]string{}
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		m := re.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+
+		var name strings.Builder
+		if err := tmpl.Execute(&name, renameMatch{Match: m[0], Groups: m[1:]}); err != nil {
+			return nil, fmt.Errorf("render name for %s: %w", entry.Name(), err)
+		}
+		to := name.String()
+		if to == "" || strings.Contains(to, "/") {
+			return nil, fmt.Errorf("invalid name %q rendered for %s", to, entry.Name())
+		}
+		if to == entry.Name() {
+			continue
+		}
+		if existing[to] {
+			return nil, fmt.Errorf("renaming %s would overwrite %s", entry.Name(), to)
+		}
+		if other, ok := targets[to]; ok {
+			return nil, fmt.Errorf("%s and %s would both be renamed to %s", other, entry.Name(), to)
+		}
+		targets[to] = entry.Name()
+		renames = append(renames, rename{from: entry.Name(), to: to})
+	}
+
+	renamed := []string{}
+	for _, r := range renames {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		from, to := path.Join(remoteDirPath, r.from), path.Join(remoteDirPath, r.to)
+		if err := c.client().Rename(from, to); err != nil {
+			return nil, fmt.Errorf("rename %s after %d of %d files: %w", from, len(renamed), len(renames), err)
+		}
+		renamed = append(renamed, from+" -> "+to)
+	}
+	return renamed, nil
+}
+
+// RenamePatternJSON is RenamePattern with the result additionally
+// marshalled to JSON when ConnectionOptions.JSONMode is set
+func (c *Connection) RenamePatternJSON(remoteDirPath, fromPattern, toTemplate string) ([]string, string, error) {
+	renamed, err := c.RenamePattern(remoteDirPath, fromPattern, toTemplate)
+	return withJSON(c, renamed, err)
+}
@@ -0,0 +1,83 @@
+package sftp
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestConnection_RenamePattern verifies matching files are renamed from the
+// template and collisions are caught before anything is renamed
+func TestConnection_RenamePattern(t *testing.T) {
+	server := NewMockServer(t)
+	opts := server.Options()
+	opts.JSONMode = true
+	conn, err := (&Client{}).ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	for _, name := range []string{"report-2.csv", "report-1.csv", "notes.txt"} {
+		server.WriteFile(t, "/out/"+name, []byte(name))
+	}
+
+	t.Run("Matching files are renamed", func(t *testing.T) {
+		renamed, jsonStr, err := conn.RenamePatternJSON("/out", `^report-(\d+)\.csv$`, `{{index .Groups 0}}-{{.Match}}`)
+		if err != nil {
+			t.Fatalf("RenamePattern failed: %v", err)
+		}
+		want := []string{
+			"/out/report-1.csv -> /out/1-report-1.csv",
+			"/out/report-2.csv -> /out/2-report-2.csv",
+		}
+		if !reflect.DeepEqual(renamed, want) {
+			t.Errorf("expected %v, got %v", want, renamed)
+		}
+		var decoded []string
+		if err := json.Unmarshal([]byte(jsonStr), &decoded); err != nil || !reflect.DeepEqual(decoded, want) {
+			t.Errorf("unexpected JSON %s (err=%v)", jsonStr, err)
+		}
+		if got := string(server.ReadFile(t, "/out/1-report-1.csv")); got != "report-1.csv" {
+			t.Errorf("expected renamed content, got %q", got)
+		}
+		if _, err := os.Stat(server.localPath("/out/notes.txt")); err != nil {
+			t.Errorf("expected notes.txt untouched, got: %v", err)
+		}
+	})
+
+	t.Run("Collisions rename nothing", func(t *testing.T) {
+		_, err := conn.RenamePattern("/out", `\.csv$`, `same.csv`)
+		if err == nil || !strings.Contains(err.Error(), "would both be renamed to same.csv") {
+			t.Errorf("expected collision error, got: %v", err)
+		}
+		_, err = conn.RenamePattern("/out", `^notes`, `1-report-1.csv`)
+		if err == nil || !strings.Contains(err.Error(), "would overwrite") {
+			t.Errorf("expected overwrite error, got: %v", err)
+		}
+		if _, err := os.Stat(server.localPath("/out/2-report-2.csv")); err != nil {
+			t.Errorf("expected files untouched, got: %v", err)
+		}
+	})
+
+	t.Run("Invalid pattern or template returns error", func(t *testing.T) {
+		if _, err := conn.RenamePattern("/out", `(`, `x`); err == nil {
+			t.Error("expected error for an invalid pattern")
+		}
+		if _, err := conn.RenamePattern("/out", `csv`, `{{.Missing}}`); err == nil {
+			t.Error("expected error for an unknown template field")
+		}
+		if _, err := conn.RenamePattern("/out", `notes`, `sub/notes`); err == nil {
+			t.Error("expected error for a name containing a slash")
+		}
+	})
+
+	t.Run("RenamePattern returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).RenamePattern("/out", `x`, `y`)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
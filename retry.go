@@ -0,0 +1,90 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// defaultRetryAttempts is the number of attempts when MaxAttempts is unset
+	defaultRetryAttempts = 3
+	// defaultRetryDelay is the delay before the first retry
+	defaultRetryDelay = 100 * time.Millisecond
+	// defaultRetryMaxDelay caps the exponential backoff
+	defaultRetryMaxDelay = 5 * time.Second
+)
+
+// RetryOptions configures retries with exponential backoff
+// The delay doubles after each failed attempt, starting at InitialDelay and
+// capped at MaxDelay. Durations from JavaScript are in nanoseconds
+type RetryOptions struct {
+	MaxAttempts  int           `js:"maxAttempts"`
+	InitialDelay time.Duration `js:"initialDelay"`
+	MaxDelay     time.Duration `js:"maxDelay"`
+}
+
+// withDefaults returns a copy of the options with unset fields defaulted
+func (ro RetryOptions) withDefaults() RetryOptions {
+	if ro.MaxAttempts <= 0 {
+		ro.MaxAttempts = defaultRetryAttempts
+	}
+	if ro.InitialDelay <= 0 {
+		ro.InitialDelay = defaultRetryDelay
+	}
+	if ro.MaxDelay <= 0 {
+		ro.MaxDelay = defaultRetryMaxDelay
+	}
+	return ro
+}
+
+// retryWithBackoff calls fn until it succeeds or MaxAttempts is reached,
+// returning the last error
+// Errors that another attempt cannot fix, see isPermanent, are returned at
+// once, and the backoff is cut short with operation aborted once ctx is done
+func retryWithBackoff(ctx context.Context, ro RetryOptions, fn func() error) error {
+	ro = ro.withDefaults()
+
+	delay := ro.InitialDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || isPermanent(err) || attempt >= ro.MaxAttempts {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("operation aborted: %w", errors.Join(ctx.Err(), err))
+		}
+		delay = min(delay*2, ro.MaxDelay)
+	}
+}
+
+// isPermanent reports whether err fails the same way on every attempt, e.g.
+// a path the policy denies or a missing file
+func isPermanent(err error) bool {
+	return errors.Is(err, ErrPathDenied) ||
+		errors.Is(err, ErrWriteNotAllowed) ||
+		errors.Is(err, ErrRemoteNotFound) ||
+		errors.Is(err, os.ErrNotExist) ||
+		errors.Is(err, os.ErrPermission)
+}
+
+// UploadManyRetry uploads each file in files (remote path -> content),
+// retrying a failed file on its own with exponential backoff
+// Returns the last error of every file that still failed after
+// ro.MaxAttempts attempts, or at once for a permanent error such as a denied
+// path; files missing from the map were uploaded
+func (c *Connection) UploadManyRetry(files map[string][]byte, ro RetryOptions) map[string]error {
+	errs := make(map[string]error)
+	for remotePath, data := range files {
+		if err := retryWithBackoff(c.vuContext(), ro, func() error {
+			return c.Upload(data, remotePath)
+		}); err != nil {
+			errs[remotePath] = err
+		}
+	}
+	return errs
+}
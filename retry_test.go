@@ -0,0 +1,100 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestRetryWithBackoff verifies attempts are capped and delays grow
+func TestRetryWithBackoff(t *testing.T) {
+	t.Run("Stops at the first success", func(t *testing.T) {
+		calls := 0
+		err := retryWithBackoff(context.Background(), RetryOptions{MaxAttempts: 5, InitialDelay: time.Millisecond}, func() error {
+			calls++
+			if calls < 3 {
+				return errors.New("transient")
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Errorf("expected success after 3 calls, got %d calls and error %v", calls, err)
+		}
+	})
+
+	t.Run("Returns the last error after MaxAttempts", func(t *testing.T) {
+		calls := 0
+		start := time.Now()
+		err := retryWithBackoff(context.Background(), RetryOptions{MaxAttempts: 3, InitialDelay: 10 * time.Millisecond}, func() error {
+			calls++
+			return errors.New("permanent")
+		})
+		if err == nil || calls != 3 {
+			t.Errorf("expected error after 3 calls, got %d calls and error %v", calls, err)
+		}
+		// 10ms + 20ms of backoff between the three attempts
+		if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+			t.Errorf("expected at least 30ms of backoff, got %v", elapsed)
+		}
+	})
+
+	t.Run("Permanent errors are not retried", func(t *testing.T) {
+		for _, permanent := range []error{ErrPathDenied, ErrWriteNotAllowed, os.ErrNotExist, os.ErrPermission} {
+			calls := 0
+			err := retryWithBackoff(context.Background(), RetryOptions{MaxAttempts: 5, InitialDelay: time.Millisecond}, func() error {
+				calls++
+				return fmt.Errorf("upload: %w", permanent)
+			})
+			if !errors.Is(err, permanent) || calls != 1 {
+				t.Errorf("expected one call failing with %v, got %d calls and error %v", permanent, calls, err)
+			}
+		}
+	})
+
+	t.Run("Ended context stops the backoff", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		start := time.Now()
+		err := retryWithBackoff(ctx, RetryOptions{MaxAttempts: 5, InitialDelay: time.Minute}, func() error {
+			calls++
+			cancel()
+			return errors.New("transient")
+		})
+		if !errors.Is(err, context.Canceled) || calls != 1 {
+			t.Errorf("expected one call and context.Canceled, got %d calls and error %v", calls, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected the backoff to be cut short, waited %v", elapsed)
+		}
+	})
+}
+
+// TestConnection_UploadManyRetry verifies failed files are retried on their
+// own and only persistent failures are reported
+func TestConnection_UploadManyRetry(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	server.FailOpens("/flaky.txt", 2)
+	server.FailOpens("/broken.txt", 10)
+
+	files := map[string][]byte{
+		"/ok.txt":     []byte("ok"),
+		"/flaky.txt":  []byte("flaky"),
+		"/broken.txt": []byte("broken"),
+	}
+	errs := conn.UploadManyRetry(files, RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond})
+
+	if len(errs) != 1 || errs["/broken.txt"] == nil {
+		t.Fatalf("expected only /broken.txt to fail, got %v", errs)
+	}
+	if got := string(server.ReadFile(t, "/ok.txt")); got != "ok" {
+		t.Errorf("expected 'ok', got %q", got)
+	}
+	if got := string(server.ReadFile(t, "/flaky.txt")); got != "flaky" {
+		t.Errorf("expected 'flaky' after retries, got %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+package sftp
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// RoundRobinConn spreads Upload, Download and Ls calls across a fixed set of
+// connections, one connection per call in turn
+// Unlike Pool it never dials or blocks; the connections stay owned by the
+// caller and must be closed by them
+type RoundRobinConn struct {
+	conns []*Connection
+	next  atomic.Uint64
+}
+
+// RoundRobinPool returns a RoundRobinConn cycling through conns in order,
+// e.g. to spread load uniformly across N servers
+func RoundRobinPool(conns []*Connection) *RoundRobinConn {
+	return &RoundRobinConn{conns: append([]*Connection(nil), conns...)}
+}
+
+// pick returns the connection for the next call
+func (r *RoundRobinConn) pick() (*Connection, error) {
+	if len(r.conns) == 0 {
+		return nil, errors.New("no connections")
+	}
+	n := r.next.Add(1) - 1
+	return r.conns[n%uint64(len(r.conns))], nil
+}
+
+// Upload writes data to remotePath on the next connection
+func (r *RoundRobinConn) Upload(data []byte, remotePath string) error {
+	conn, err := r.pick()
+	if err != nil {
+		return err
+	}
+	return conn.Upload(data, remotePath)
+}
+
+// Download copies remotePath to localPath from the next connection
+func (r *RoundRobinConn) Download(remotePath, localPath string) error {
+	conn, err := r.pick()
+	if err != nil {
+		return err
+	}
+	return conn.Download(remotePath, localPath)
+}
+
+// Ls lists path on the next connection
+func (r *RoundRobinConn) Ls(path string) (*LsResult, error) {
+	conn, err := r.pick()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Ls(path)
+}
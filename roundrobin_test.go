@@ -0,0 +1,57 @@
+package sftp
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestRoundRobinPool verifies successive calls are spread evenly across the
+// connections
+func TestRoundRobinPool(t *testing.T) {
+	servers := []*MockServer{NewMockServer(t), NewMockServer(t), NewMockServer(t)}
+	conns := make([]*Connection, len(servers))
+	for i, server := range servers {
+		conns[i] = server.Connect(t)
+	}
+	rr := RoundRobinPool(conns)
+
+	t.Run("Uploads rotate through the connections", func(t *testing.T) {
+		for i := 0; i < 2*len(servers); i++ {
+			if err := rr.Upload([]byte("data"), fmt.Sprintf("/rr-%d.txt", i)); err != nil {
+				t.Fatalf("Upload %d failed: %v", i, err)
+			}
+		}
+		for i := 0; i < 2*len(servers); i++ {
+			server := servers[i%len(servers)]
+			if got := string(server.ReadFile(t, fmt.Sprintf("/rr-%d.txt", i))); got != "data" {
+				t.Errorf("expected /rr-%d.txt on server %d, got %q", i, i%len(servers), got)
+			}
+		}
+	})
+
+	t.Run("Concurrent calls are distributed uniformly", func(t *testing.T) {
+		const perServer = 5
+		var wg sync.WaitGroup
+		for i := 0; i < perServer*len(servers); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := rr.Ls("/"); err != nil {
+					t.Errorf("Ls failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if n := rr.next.Load(); n != uint64(2*len(servers)+perServer*len(servers)) {
+			t.Errorf("expected %d calls counted, got %d", 2*len(servers)+perServer*len(servers), n)
+		}
+	})
+
+	t.Run("Empty pool returns an error", func(t *testing.T) {
+		if err := RoundRobinPool(nil).Upload([]byte("data"), "/none.txt"); err == nil {
+			t.Error("expected error for an empty pool")
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ErrNoRoute is returned when no route matches a remote path
+var ErrNoRoute = errors.New("no route for path")
+
+// Router picks a Connection by remote path, so scripts can address several
+// servers through one object, e.g. /prod/* to one server and /staging/* to
+// another
+type Router struct {
+	mu      sync.RWMutex
+	aliases map[string]*Connection
+	routes  []route
+}
+
+// route maps one path pattern to a connection
+type route struct {
+	pattern string
+	conn    *Connection
+}
+
+// NewRouter returns a Router without routes or aliases
+func NewRouter() *Router {
+	return &Router{aliases: map[string]*Connection{}}
+}
+
+// Alias registers conn under name, replacing any connection already there
+func (r *Router) Alias(name string, conn *Connection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.aliases[name] = conn
+}
+
+// Get returns the connection registered with Alias under name
+func (r *Router) Get(name string) (*Connection, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conn, ok := r.aliases[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown alias %q", name)
+	}
+	return conn, nil
+}
+
+// Route sends paths matching pattern to conn. Patterns use path.Match
+// syntax, except that a trailing /* also matches everything below the
+// directory. Routes are tried in the order they were added
+func (r *Router) Route(pattern string, conn *Connection) error {
+	if _, err := path.Match(pattern, "/"); err != nil {
+		return fmt.Errorf("invalid route pattern %q: %w", pattern, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route{pattern: pattern, conn: conn})
+	return nil
+}
+
+// Resolve returns the connection of the first route matching remotePath
+func (r *Router) Resolve(remotePath string) (*Connection, error) {
+	remotePath = path.Clean("/" + remotePath)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rt := range r.routes {
+		if matchRoute(rt.pattern, remotePath) {
+			return rt.conn, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoRoute, remotePath)
+}
+
+// Upload writes data to dstPath through the connection routed for it
+func (r *Router) Upload(data []byte, dstPath string) error {
+	conn, err := r.Resolve(dstPath)
+	if err != nil {
+		return err
+	}
+	return conn.Upload(data, dstPath)
+}
+
+// matchRoute reports whether remotePath matches a route pattern
+func matchRoute(pattern, remotePath string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/*"); ok {
+		if dir == "" {
+			dir = "/"
+		}
+		// Match any ancestor directory, so files at every depth are routed
+		for p := path.Dir(remotePath); ; p = path.Dir(p) {
+			if matched, _ := path.Match(dir, p); matched {
+				return true
+			}
+			if p == "/" {
+				return false
+			}
+		}
+	}
+
+	matched, _ := path.Match(pattern, remotePath)
+	return matched
+}
@@ -0,0 +1,76 @@
+package sftp
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestRouter verifies uploads reach the connection whose route matches the
+// destination path
+func TestRouter(t *testing.T) {
+	prodServer, stagingServer := NewMockServer(t), NewMockServer(t)
+	prod, staging := prodServer.Connect(t), stagingServer.Connect(t)
+
+	router := NewRouter()
+	if err := router.Route("/prod/*", prod); err != nil {
+		t.Fatalf("Route failed: %v", err)
+	}
+	if err := router.Route("/staging/*", staging); err != nil {
+		t.Fatalf("Route failed: %v", err)
+	}
+	router.Alias("prod", prod)
+
+	t.Run("Uploads follow the routes", func(t *testing.T) {
+		if err := prod.client().Mkdir("/prod"); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := staging.client().Mkdir("/staging"); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+
+		if err := router.Upload([]byte("p"), "/prod/app.cfg"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if err := router.Upload([]byte("s"), "/staging/app.cfg"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if got := string(prodServer.ReadFile(t, "/prod/app.cfg")); got != "p" {
+			t.Errorf("expected 'p' on prod, got %q", got)
+		}
+		if got := string(stagingServer.ReadFile(t, "/staging/app.cfg")); got != "s" {
+			t.Errorf("expected 's' on staging, got %q", got)
+		}
+	})
+
+	t.Run("Trailing wildcard matches nested paths", func(t *testing.T) {
+		conn, err := router.Resolve("/staging/a/b/c.txt")
+		if err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		if conn != staging {
+			t.Error("expected nested path to route to staging")
+		}
+	})
+
+	t.Run("Unrouted path returns ErrNoRoute", func(t *testing.T) {
+		if err := router.Upload([]byte("x"), "/dev/app.cfg"); !errors.Is(err, ErrNoRoute) {
+			t.Errorf("expected ErrNoRoute, got: %v", err)
+		}
+	})
+
+	t.Run("Aliases look up connections by name", func(t *testing.T) {
+		conn, err := router.Get("prod")
+		if err != nil || conn != prod {
+			t.Errorf("expected prod connection, got %v (err=%v)", conn, err)
+		}
+		if _, err := router.Get("qa"); err == nil {
+			t.Error("expected error for unknown alias")
+		}
+	})
+
+	t.Run("Invalid pattern is rejected", func(t *testing.T) {
+		if err := router.Route("/[", prod); err == nil {
+			t.Error("expected error for invalid pattern")
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pkg/sftp"
+)
+
+// OpenReadSession opens an extra SFTP session over the connection's SSH
+// connection for downloads, so they do not queue behind uploads in the
+// same session's request window
+// Closing the session leaves the parent connection open; closing the parent
+// ends the session
+func (c *Connection) OpenReadSession() (*Connection, error) {
+	return c.openSession(sftp.UseConcurrentReads(true))
+}
+
+// OpenWriteSession opens an extra SFTP session like OpenReadSession, for
+// uploads. Writes to the session are sent concurrently
+func (c *Connection) OpenWriteSession() (*Connection, error) {
+	return c.openSession(sftp.UseConcurrentWrites(true))
+}
+
+// openSession opens a new sftp.Client on the connection's SSH connection,
+// sharing its options
+func (c *Connection) openSession(opts ...sftp.ClientOption) (*Connection, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+	if c.sshClient == nil {
+		return nil, errors.New("sessions require SSH, not a WebSocket")
+	}
+
+	sftpClient, err := newSFTPClient(c.sshClient, c.opts, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("sftp session creation failed: %w", err)
+	}
+
+	session := &Connection{
+		vu:         c.vu,
+		opts:       c.opts,
+		sshClient:  c.sshClient,
+		clientOpts: opts,
+		metrics:    c.metrics,
+		sharedSSH:  true,
+	}
+	session.sftpClient.Store(sftpClient)
+	session.assignConnectionID()
+	return session, nil
+}
+
+// isSessionLost reports whether err is pkg/sftp failing a request because
+// its session ended, e.g. the server closed it after an idle timeout
+func isSessionLost(err error) bool {
+	return errors.Is(err, sftp.ErrSSHFxConnectionLost) || errors.Is(err, io.EOF)
+}
+
+// withSession runs fn and, if it failed because the server closed the SFTP
+// session while the SSH connection is still up, reopens the session so
+// later operations work. With replay, fn then runs once more on the new
+// session; without, its error is returned, since fn may have got as far as
+// changing files, e.g. appended part of its data or renamed some of them
+func withSession[T any](c *Connection, replay bool, fn func() (T, error)) (T, error) {
+	failed := c.client()
+	val, err := fn()
+	if err == nil || !isSessionLost(err) || !c.reopenSession(failed) || !replay {
+		return val, err
+	}
+	return fn()
+}
+
+// reopenSession replaces failed, the client an operation just failed on,
+// with a new sftp.Client over the existing SSH connection, without a new
+// SSH handshake. Reports whether the connection has a working session
+// Nothing is reopened unless failed is really closed and the SSH
+// connection still answers, since a dropped SSH connection and a plain
+// io.EOF from a request look the same to the caller
+func (c *Connection) reopenSession(failed *sftp.Client) bool {
+	if failed == nil {
+		return false
+	}
+
+	// Probes and the new session are network round trips, so they run under
+	// reopenMu, which only concurrent reopens wait for. sessionMu is held
+	// just to swap the client, so Close is never stuck behind a slow server
+	c.reopenMu.Lock()
+	defer c.reopenMu.Unlock()
+	if current := c.client(); current != failed {
+		// Reopened by a concurrent operation, or closed
+		return current != nil
+	}
+	c.sessionMu.Lock()
+	sshClient := c.sshClient
+	c.sessionMu.Unlock()
+	if sshClient == nil || c.closing.Load() {
+		return false
+	}
+	if _, err := failed.Getwd(); !isSessionLost(err) {
+		return false
+	}
+	if _, _, err := sshClient.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+		return false
+	}
+
+	sftpClient, err := newSFTPClient(sshClient, c.opts, c.clientOpts...)
+	if err != nil {
+		if logger := c.logger(); logger != nil {
+			logger.WithError(err).Warn("sftp: server closed the session and it could not be reopened")
+		}
+		return false
+	}
+
+	c.sessionMu.Lock()
+	if c.closing.Load() || !c.sftpClient.CompareAndSwap(failed, sftpClient) {
+		// Closed while the session was being opened
+		c.sessionMu.Unlock()
+		sftpClient.Close()
+		return false
+	}
+	previousID := c.ConnectionID()
+	c.assignConnectionID()
+	c.sessionMu.Unlock()
+
+	failed.Close()
+	if logger := c.logger(); logger != nil {
+		logger.WithField("previous_conn_id", previousID).
+			Info("sftp: server closed the session, reopened it over the same SSH connection")
+	}
+	return true
+}
@@ -0,0 +1,254 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConnection_Sessions verifies read and write sessions share the SSH
+// connection and outlive their own Close
+func TestConnection_Sessions(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("Sessions share one SSH connection", func(t *testing.T) {
+		writer, err := conn.OpenWriteSession()
+		if err != nil {
+			t.Fatalf("OpenWriteSession failed: %v", err)
+		}
+		defer writer.Close()
+		reader, err := conn.OpenReadSession()
+		if err != nil {
+			t.Fatalf("OpenReadSession failed: %v", err)
+		}
+		defer reader.Close()
+
+		if err := writer.Upload([]byte("session"), "/session.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if got, err := reader.readAll(context.Background(), "/session.txt"); err != nil || string(got) != "session" {
+			t.Errorf("expected 'session' from the read session, got %q (err=%v)", got, err)
+		}
+		if got := server.ConnCount(); got != 1 {
+			t.Errorf("expected 1 TCP connection, got %d", got)
+		}
+	})
+
+	t.Run("Closing a session leaves the connection open", func(t *testing.T) {
+		session, err := conn.OpenReadSession()
+		if err != nil {
+			t.Fatalf("OpenReadSession failed: %v", err)
+		}
+		if err := session.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if _, err := conn.Stat("/session.txt"); err != nil {
+			t.Errorf("expected parent connection to work, got: %v", err)
+		}
+	})
+
+	t.Run("OpenReadSession returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).OpenReadSession()
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// BenchmarkSessions compares interleaved uploads and downloads over one
+// SFTP session with the same traffic split across read and write sessions
+func BenchmarkSessions(b *testing.B) {
+	payload := bytes.Repeat([]byte("x"), 1<<20)
+
+	run := func(b *testing.B, reader, writer *Connection) {
+		if err := writer.Upload(payload, "/bench-read.bin"); err != nil {
+			b.Fatalf("seed upload failed: %v", err)
+		}
+		b.SetBytes(int64(2 * len(payload)))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if err := writer.Upload(payload, "/bench-write.bin"); err != nil {
+					b.Error(err)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if _, err := reader.readAll(context.Background(), "/bench-read.bin"); err != nil {
+					b.Error(err)
+				}
+			}()
+			wg.Wait()
+		}
+	}
+
+	b.Run("Single session", func(b *testing.B) {
+		conn := NewMockServer(b).Connect(b)
+		run(b, conn, conn)
+	})
+
+	b.Run("Read and write sessions", func(b *testing.B) {
+		conn := NewMockServer(b).Connect(b)
+		reader, err := conn.OpenReadSession()
+		if err != nil {
+			b.Fatalf("OpenReadSession failed: %v", err)
+		}
+		defer reader.Close()
+		writer, err := conn.OpenWriteSession()
+		if err != nil {
+			b.Fatalf("OpenWriteSession failed: %v", err)
+		}
+		defer writer.Close()
+		run(b, reader, writer)
+	})
+}
+
+// TestConnection_SessionRecovery verifies operations survive the server
+// closing the SFTP session by reopening it over the same SSH connection
+func TestConnection_SessionRecovery(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/data.txt", []byte("data"))
+
+	t.Run("Stat reopens the session", func(t *testing.T) {
+		before, beforeID := conn.client(), conn.ConnectionID()
+		server.RestartSFTP()
+
+		if _, err := conn.Stat("/data.txt"); err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if conn.client() == before {
+			t.Error("expected a new sftp.Client")
+		}
+		if conn.ConnectionID() == beforeID {
+			t.Error("expected a new connection ID")
+		}
+		if got := server.ConnCount(); got != 1 {
+			t.Errorf("expected the SSH connection to be reused, got %d TCP connections", got)
+		}
+	})
+
+	t.Run("Upload and Download reopen the session", func(t *testing.T) {
+		server.RestartSFTP()
+		if err := conn.Upload([]byte("after restart"), "/restart.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+
+		server.RestartSFTP()
+		localPath := filepath.Join(t.TempDir(), "restart.txt")
+		if err := conn.Download("/restart.txt", localPath); err != nil {
+			t.Fatalf("Download failed: %v", err)
+		}
+		if got, _ := os.ReadFile(localPath); string(got) != "after restart" {
+			t.Errorf("expected 'after restart', got %q", got)
+		}
+	})
+
+	t.Run("Other operations reopen the session", func(t *testing.T) {
+		server.WriteFile(t, "/tree/a.txt", []byte("a\nb\n"))
+
+		server.RestartSFTP()
+		if lines, err := conn.CountLines("/tree/a.txt"); err != nil || lines != 2 {
+			t.Errorf("expected CountLines to return 2, got %d (err=%v)", lines, err)
+		}
+		server.RestartSFTP()
+		if matches, err := conn.Grep("/tree/a.txt", "b"); err != nil || len(matches) != 1 {
+			t.Errorf("expected Grep to return 1 match, got %v (err=%v)", matches, err)
+		}
+		server.RestartSFTP()
+		if err := conn.UploadResume([]byte("a\nb\nc\n"), "/tree/a.txt"); err != nil {
+			t.Errorf("UploadResume failed: %v", err)
+		}
+		if removed, err := conn.RemoveAll("/tree"); err != nil || removed != 2 {
+			t.Errorf("expected RemoveAll to remove 2 entries, got %d (err=%v)", removed, err)
+		}
+	})
+
+	t.Run("Non-idempotent operations are not replayed", func(t *testing.T) {
+		server.WriteFile(t, "/tree/a.txt", []byte("a"))
+
+		server.RestartSFTP()
+		if _, err := conn.RemoveAll("/tree"); !isSessionLost(err) {
+			t.Errorf("expected the lost session's error, got: %v", err)
+		}
+		if removed, err := conn.RemoveAll("/tree"); err != nil || removed != 2 {
+			t.Errorf("expected RemoveAll to remove 2 entries on the reopened session, got %d (err=%v)", removed, err)
+		}
+	})
+
+	t.Run("Concurrent operations reopen the session once", func(t *testing.T) {
+		before := conn.client()
+		server.RestartSFTP()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := conn.Stat("/data.txt")
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Errorf("Stat failed: %v", err)
+			}
+		}
+		if conn.client() == before {
+			t.Error("expected a new sftp.Client")
+		}
+	})
+
+	t.Run("Other errors are not retried", func(t *testing.T) {
+		before := conn.client()
+		if _, err := conn.Stat("/missing.txt"); !errors.Is(err, ErrRemoteNotFound) {
+			t.Errorf("expected ErrRemoteNotFound, got: %v", err)
+		}
+		if conn.client() != before {
+			t.Error("expected the session to be kept")
+		}
+	})
+
+	t.Run("A dropped append is not written twice", func(t *testing.T) {
+		server := NewMockServer(t)
+		server.WriteLatency = 5 * time.Millisecond
+		conn := server.Connect(t)
+		server.WriteFile(t, "/log.txt", []byte("seed\n"))
+
+		data := bytes.Repeat([]byte("x"), 2<<20)
+		time.AfterFunc(100*time.Millisecond, server.RestartSFTP)
+		err := conn.UploadWithFlags(data, "/log.txt", FlagWrite|FlagAppend)
+		if !isSessionLost(err) {
+			t.Fatalf("expected the lost session's error, got: %v", err)
+		}
+
+		got := server.ReadFile(t, "/log.txt")
+		if len(got) <= len("seed\n") || len(got) >= len("seed\n")+len(data) {
+			t.Errorf("expected part of the data appended once, got %d bytes", len(got))
+		}
+		if _, err := conn.Stat("/log.txt"); err != nil {
+			t.Errorf("expected the session to be reopened, got: %v", err)
+		}
+	})
+
+	t.Run("Dropped SSH connection is not recovered", func(t *testing.T) {
+		dropped := server.Connect(t)
+		dropped.sshClient.Close()
+
+		if _, err := dropped.Stat("/data.txt"); err == nil {
+			t.Error("expected an error once the SSH connection is gone")
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TreeSnapshot records every regular file below a remote directory, so the
+// tree can be compared before and after a scenario
+type TreeSnapshot struct {
+	Root string `js:"root"`
+	// Files maps each file's path relative to Root to its snapshot
+	Files map[string]FileSnapshot `js:"files"`
+}
+
+// FileSnapshot describes one file of a TreeSnapshot
+type FileSnapshot struct {
+	Size    int64  `js:"size"`
+	ModTime int64  `js:"modTime"`
+	SHA256  string `js:"sha256"`
+}
+
+// DiffReport lists the relative paths that differ between two snapshots,
+// each sorted
+type DiffReport struct {
+	Created  []string `js:"created"`
+	Modified []string `js:"modified"`
+	Deleted  []string `js:"deleted"`
+}
+
+// SnapshotTree walks the tree rooted at rootPath and records the size,
+// modification time and SHA-256 of every regular file
+// Every file is read in full to hash it, so snapshot small fixture trees
+// rather than large data directories
+func (c *Connection) SnapshotTree(rootPath string) (_ *TreeSnapshot, err error) {
+	defer c.observe("snapshotTree", rootPath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (*TreeSnapshot, error) { return c.snapshotTree(ctx, rootPath) })
+}
+
+func (c *Connection) snapshotTree(ctx context.Context, rootPath string) (*TreeSnapshot, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+	rootPath = c.resolvePath(rootPath)
+
+	snapshot := &TreeSnapshot{Root: rootPath, Files: map[string]FileSnapshot{}}
+	walker := c.client().Walk(rootPath)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := walker.Err(); err != nil {
+			return nil, fmt.Errorf("walk %s: %w", walker.Path(), err)
+		}
+		info := walker.Stat()
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		digest, err := c.hashFile(ctx, walker.Path(), SHA256Hasher{})
+		if err != nil {
+			return nil, err
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), path.Clean(rootPath)), "/")
+		snapshot.Files[rel] = FileSnapshot{
+			Size:    info.Size(),
+			ModTime: info.ModTime().Unix(),
+			SHA256:  digest,
+		}
+	}
+
+	return snapshot, nil
+}
+
+// Diff compares s, taken before, with other, taken after: files only in
+// other are created, files only in s deleted, and files whose content
+// changed modified. A changed modification time alone is not reported
+func (s *TreeSnapshot) Diff(other *TreeSnapshot) *DiffReport {
+	report := &DiffReport{Created: []string{}, Modified: []string{}, Deleted: []string{}}
+
+	for name, before := range s.Files {
+		after, ok := other.Files[name]
+		switch {
+		case !ok:
+			report.Deleted = append(report.Deleted, name)
+		case after.SHA256 != before.SHA256:
+			report.Modified = append(report.Modified, name)
+		}
+	}
+	for name := range other.Files {
+		if _, ok := s.Files[name]; !ok {
+			report.Created = append(report.Created, name)
+		}
+	}
+
+	slices.Sort(report.Created)
+	slices.Sort(report.Modified)
+	slices.Sort(report.Deleted)
+	return report
+}
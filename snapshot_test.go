@@ -0,0 +1,77 @@
+package sftp
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestConnection_SnapshotTree verifies snapshots record every file and the
+// diff reports created, modified and deleted files
+func TestConnection_SnapshotTree(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	if err := conn.client().MkdirAll("/tree/sub"); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	server.WriteFile(t, "/tree/keep.txt", []byte("keep"))
+	server.WriteFile(t, "/tree/change.txt", []byte("before"))
+	server.WriteFile(t, "/tree/sub/remove.txt", []byte("remove"))
+
+	before, err := conn.SnapshotTree("/tree")
+	if err != nil {
+		t.Fatalf("SnapshotTree failed: %v", err)
+	}
+
+	t.Run("Snapshot records every file", func(t *testing.T) {
+		if len(before.Files) != 3 {
+			t.Fatalf("expected 3 files, got %v", before.Files)
+		}
+		keep, ok := before.Files["keep.txt"]
+		if !ok {
+			t.Fatalf("expected keep.txt in %v", before.Files)
+		}
+		// sha256("keep")
+		if keep.Size != 4 || keep.SHA256 != "6ca7ea2feefc88ecb5ed6356ed963f47dc9137f82526fdd25d618ea626d0803f" {
+			t.Errorf("unexpected snapshot %+v", keep)
+		}
+		if _, ok := before.Files["sub/remove.txt"]; !ok {
+			t.Errorf("expected nested file sub/remove.txt in %v", before.Files)
+		}
+	})
+
+	t.Run("Diff reports the changes", func(t *testing.T) {
+		server.WriteFile(t, "/tree/change.txt", []byte("after"))
+		server.WriteFile(t, "/tree/sub/new.txt", []byte("new"))
+		if err := conn.client().Remove("/tree/sub/remove.txt"); err != nil {
+			t.Fatalf("remove: %v", err)
+		}
+
+		after, err := conn.SnapshotTree("/tree")
+		if err != nil {
+			t.Fatalf("SnapshotTree failed: %v", err)
+		}
+
+		want := &DiffReport{
+			Created:  []string{"sub/new.txt"},
+			Modified: []string{"change.txt"},
+			Deleted:  []string{"sub/remove.txt"},
+		}
+		if got := before.Diff(after); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("Missing root returns error", func(t *testing.T) {
+		if _, err := conn.SnapshotTree("/missing"); err == nil {
+			t.Error("expected error for missing root")
+		}
+	})
+
+	t.Run("SnapshotTree returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).SnapshotTree("/tree")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
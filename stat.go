@@ -0,0 +1,208 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// ErrWaitTimeout is returned when a wait helper gives up
+var ErrWaitTimeout = errors.New("timed out waiting for remote file")
+
+// statManyConcurrency bounds the stat requests StatMany keeps in flight
+const statManyConcurrency = 16
+
+// FileSize returns the size in bytes of a remote file
+// Returns -1 and ErrRemoteNotFound if the file does not exist
+func (c *Connection) FileSize(remotePath string) (int64, error) {
+	info, err := c.Stat(remotePath)
+	if err != nil {
+		return -1, err
+	}
+	return info["size"].(int64), nil
+}
+
+// ModTime returns the modification time of a remote file
+// Returns time.Time{} and ErrRemoteNotFound if the file does not exist
+func (c *Connection) ModTime(remotePath string) (time.Time, error) {
+	info, err := c.Stat(remotePath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(info["modTime"].(int64), 0), nil
+}
+
+// ModTimeMillis returns the modification time of a remote file as a Unix
+// millisecond timestamp, comparable with Date.now() in JavaScript
+func (c *Connection) ModTimeMillis(remotePath string) (int64, error) {
+	modTime, err := c.ModTime(remotePath)
+	if err != nil {
+		return 0, err
+	}
+	return modTime.UnixMilli(), nil
+}
+
+// AccessTime returns the last access time the server reports for a remote
+// path, without following symlinks
+// Returns time.Time{} if the server sent no access time, and
+// ErrRemoteNotFound if the path does not exist
+func (c *Connection) AccessTime(remotePath string) (_ time.Time, err error) {
+	defer c.observe("accessTime", remotePath, time.Now(), &err)
+	return withReplay(c, func(context.Context) (time.Time, error) { return c.accessTime(remotePath) })
+}
+
+func (c *Connection) accessTime(remotePath string) (time.Time, error) {
+	if c.client() == nil {
+		return time.Time{}, errors.New("not connected")
+	}
+
+	info, err := c.client().Lstat(c.resolvePath(remotePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrRemoteNotFound, remotePath)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("stat remote file: %w", err)
+	}
+	return remoteAccessTime(info.Sys()), nil
+}
+
+// remoteAccessTime extracts the access time from the Sys() value of a
+// remote file's info, or returns time.Time{} if there is none. pkg/sftp
+// leaves Atime zero when the server sent no times
+func remoteAccessTime(sys interface{}) time.Time {
+	stat, ok := sys.(*sftp.FileStat)
+	if !ok || stat.Atime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(stat.Atime), 0)
+}
+
+// UploadStat uploads data to remotePath and returns the uploaded file's
+// Stat properties plus createdAt, a Unix millisecond timestamp comparable
+// with Date.now() in JavaScript
+// SFTP version 3, the only one pkg/sftp speaks, has no creation time
+// attribute, so createdAt is when the upload completed by the client's
+// clock. Compare modTime, set by the server, to check the server's clock
+func (c *Connection) UploadStat(data []byte, remotePath string) (_ map[string]interface{}, err error) {
+	defer c.observe("uploadStat", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (map[string]interface{}, error) { return c.uploadStat(ctx, data, remotePath) })
+}
+
+func (c *Connection) uploadStat(ctx context.Context, data []byte, remotePath string) (map[string]interface{}, error) {
+	if err := c.upload(ctx, data, remotePath); err != nil {
+		return nil, err
+	}
+	createdAt := time.Now()
+
+	info, err := c.stat(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	info["createdAt"] = createdAt.UnixMilli()
+	return info, nil
+}
+
+// StatMany stats several remote paths over the connection's SFTP session
+// Requests are pipelined rather than issued one round trip at a time
+// Missing paths map to nil; other failures are joined into the error and
+// their paths are left out of the result
+func (c *Connection) StatMany(paths []string) (_ map[string]map[string]interface{}, err error) {
+	defer c.observe("statMany", "", time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (map[string]map[string]interface{}, error) { return c.statMany(ctx, paths) })
+}
+
+func (c *Connection) statMany(ctx context.Context, paths []string) (map[string]map[string]interface{}, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]map[string]interface{}, len(paths))
+		errs    []error
+		sem     = make(chan struct{}, statManyConcurrency)
+	)
+
+	for _, p := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			info, err := c.stat(p)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, ErrRemoteNotFound):
+				results[p] = nil
+			case err != nil:
+				errs = append(errs, fmt.Errorf("%s: %w", p, err))
+			default:
+				results[p] = info
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
+
+// IsDir reports whether a remote path is a directory
+// Returns ErrRemoteNotFound if the path does not exist
+func (c *Connection) IsDir(remotePath string) (bool, error) {
+	info, err := c.Stat(remotePath)
+	if err != nil {
+		return false, err
+	}
+	return info["isDir"].(bool), nil
+}
+
+// WaitForFileSize polls the size of a remote file until it reaches at least
+// minSize bytes, e.g. while the system under test is still writing it
+// The file may not exist yet when polling starts. Polls every
+// ConnectionOptions.PollInterval and returns ErrWaitTimeout once timeout
+// has elapsed
+func (c *Connection) WaitForFileSize(remotePath string, minSize int64, timeout time.Duration) (err error) {
+	defer c.observe("waitForFileSize", remotePath, time.Now(), &err)
+
+	interval := c.opts.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		// Not going through FileSize, so polls for a missing file are not
+		// reported to the OnError hook
+		size := int64(-1)
+		info, err := withReplay(c, func(context.Context) (map[string]interface{}, error) { return c.stat(remotePath) })
+		switch {
+		case err == nil:
+			size = info["size"].(int64)
+		case !errors.Is(err, ErrRemoteNotFound):
+			return err
+		}
+		if size >= minSize {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w: %s has %d bytes, want at least %d", ErrWaitTimeout, remotePath, max(size, 0), minSize)
+		}
+		if err := c.sleep(min(interval, remaining)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// TestConnection_Stat verifies file info is returned for existing paths and
+// ErrRemoteNotFound for missing ones
+func TestConnection_Stat(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/dir/file.txt", []byte("hello"))
+
+	t.Run("Stat file", func(t *testing.T) {
+		info, err := conn.Stat("/dir/file.txt")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if info["name"] != "file.txt" || info["size"] != int64(5) || info["isDir"] != false {
+			t.Errorf("unexpected file info: %v", info)
+		}
+		if _, ok := info["modTime"]; !ok {
+			t.Error("file info missing 'modTime' field")
+		}
+	})
+
+	t.Run("Stat directory", func(t *testing.T) {
+		info, err := conn.Stat("/dir")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if info["isDir"] != true {
+			t.Errorf("expected directory, got %v", info)
+		}
+	})
+
+	t.Run("Stat missing path returns ErrRemoteNotFound", func(t *testing.T) {
+		info, err := conn.Stat("/missing.txt")
+		if !errors.Is(err, ErrRemoteNotFound) {
+			t.Errorf("expected ErrRemoteNotFound, got: %v", err)
+		}
+		if info != nil {
+			t.Error("expected nil info for missing path")
+		}
+	})
+
+	t.Run("Stat returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).Stat("/dir")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_StatMany verifies batch stat returns an entry per path
+func TestConnection_StatMany(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	paths := make([]string, 100)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/batch/file%03d.txt", i)
+		server.WriteFile(t, paths[i], []byte(strings.Repeat("x", i)))
+	}
+
+	t.Run("Stat 100 files", func(t *testing.T) {
+		results, err := conn.StatMany(paths)
+		if err != nil {
+			t.Fatalf("StatMany failed: %v", err)
+		}
+		if len(results) != len(paths) {
+			t.Fatalf("expected %d results, got %d", len(paths), len(results))
+		}
+		for i, p := range paths {
+			info := results[p]
+			if info == nil {
+				t.Errorf("missing result for %s", p)
+				continue
+			}
+			if info["size"] != int64(i) {
+				t.Errorf("expected size %d for %s, got %v", i, p, info["size"])
+			}
+		}
+	})
+
+	t.Run("Missing path maps to nil", func(t *testing.T) {
+		results, err := conn.StatMany([]string{paths[0], "/missing.txt"})
+		if err != nil {
+			t.Fatalf("StatMany failed: %v", err)
+		}
+		info, ok := results["/missing.txt"]
+		if !ok || info != nil {
+			t.Errorf("expected nil entry for missing path, got %v (present: %v)", info, ok)
+		}
+		if results[paths[0]] == nil {
+			t.Errorf("expected result for %s", paths[0])
+		}
+	})
+
+	t.Run("StatMany returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).StatMany(paths)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_FileSize verifies the size shortcut around Stat
+func TestConnection_FileSize(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/file.txt", []byte("hello world"))
+
+	t.Run("Existing file", func(t *testing.T) {
+		size, err := conn.FileSize("/file.txt")
+		if err != nil {
+			t.Fatalf("FileSize failed: %v", err)
+		}
+		if size != 11 {
+			t.Errorf("expected size 11, got %d", size)
+		}
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		size, err := conn.FileSize("/missing.txt")
+		if !errors.Is(err, ErrRemoteNotFound) {
+			t.Errorf("expected ErrRemoteNotFound, got: %v", err)
+		}
+		if size != -1 {
+			t.Errorf("expected size -1, got %d", size)
+		}
+	})
+}
+
+// TestConnection_ModTime verifies the modification time shortcuts around Stat
+func TestConnection_ModTime(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/file.txt", []byte("hello"))
+
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(server.localPath("/file.txt"), modTime, modTime); err != nil {
+		t.Fatalf("set file times: %v", err)
+	}
+
+	t.Run("ModTime returns time.Time", func(t *testing.T) {
+		got, err := conn.ModTime("/file.txt")
+		if err != nil {
+			t.Fatalf("ModTime failed: %v", err)
+		}
+		if !got.Equal(modTime) {
+			t.Errorf("expected %v, got %v", modTime, got)
+		}
+	})
+
+	t.Run("ModTimeMillis returns Unix milliseconds", func(t *testing.T) {
+		got, err := conn.ModTimeMillis("/file.txt")
+		if err != nil {
+			t.Fatalf("ModTimeMillis failed: %v", err)
+		}
+		if got != modTime.UnixMilli() {
+			t.Errorf("expected %d, got %d", modTime.UnixMilli(), got)
+		}
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		got, err := conn.ModTime("/missing.txt")
+		if !errors.Is(err, ErrRemoteNotFound) {
+			t.Errorf("expected ErrRemoteNotFound, got: %v", err)
+		}
+		if !got.IsZero() {
+			t.Errorf("expected zero time, got %v", got)
+		}
+	})
+}
+
+// TestConnection_AccessTime verifies the access time reported by the server
+// is returned, not the modification time
+func TestConnection_AccessTime(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/file.txt", []byte("hello"))
+
+	// The pkg/sftp server reports the modification time as the access
+	// time, so both are set alike here and told apart below
+	accessTime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if err := os.Chtimes(server.localPath("/file.txt"), accessTime, accessTime); err != nil {
+		t.Fatalf("set file times: %v", err)
+	}
+
+	t.Run("Returns the access time", func(t *testing.T) {
+		got, err := conn.AccessTime("/file.txt")
+		if err != nil {
+			t.Fatalf("AccessTime failed: %v", err)
+		}
+		if !got.Equal(accessTime) {
+			t.Errorf("expected %v, got %v", accessTime, got)
+		}
+	})
+
+	t.Run("Atime is used rather than Mtime", func(t *testing.T) {
+		stat := &sftp.FileStat{Atime: uint32(accessTime.Unix()), Mtime: 1000}
+		if got := remoteAccessTime(stat); !got.Equal(accessTime) {
+			t.Errorf("expected %v, got %v", accessTime, got)
+		}
+	})
+
+	t.Run("Unavailable access time is zero", func(t *testing.T) {
+		if got := remoteAccessTime(&sftp.FileStat{Mtime: 1000}); !got.IsZero() {
+			t.Errorf("expected zero time without Atime, got %v", got)
+		}
+		if got := remoteAccessTime(nil); !got.IsZero() {
+			t.Errorf("expected zero time without a FileStat, got %v", got)
+		}
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		got, err := conn.AccessTime("/missing.txt")
+		if !errors.Is(err, ErrRemoteNotFound) {
+			t.Errorf("expected ErrRemoteNotFound, got: %v", err)
+		}
+		if !got.IsZero() {
+			t.Errorf("expected zero time, got %v", got)
+		}
+	})
+
+	t.Run("AccessTime returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).AccessTime("/file.txt")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_UploadStat verifies the uploaded file is described with
+// the time the upload completed
+func TestConnection_UploadStat(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("Returns the stat and createdAt", func(t *testing.T) {
+		before := time.Now().UnixMilli()
+		info, err := conn.UploadStat([]byte("hello"), "/created.txt")
+		if err != nil {
+			t.Fatalf("UploadStat failed: %v", err)
+		}
+		after := time.Now().UnixMilli()
+
+		if info["name"] != "created.txt" || info["size"] != int64(5) {
+			t.Errorf("unexpected stat %v", info)
+		}
+		createdAt, ok := info["createdAt"].(int64)
+		if !ok || createdAt < before || createdAt > after {
+			t.Errorf("expected createdAt between %d and %d, got %v", before, after, info["createdAt"])
+		}
+		if got := string(server.ReadFile(t, "/created.txt")); got != "hello" {
+			t.Errorf("remote content mismatch: got %q", got)
+		}
+	})
+
+	t.Run("Failed upload returns error", func(t *testing.T) {
+		if _, err := conn.UploadStat([]byte("hello"), "/missing/created.txt"); err == nil {
+			t.Error("expected error for a missing directory")
+		}
+	})
+
+	t.Run("UploadStat returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).UploadStat([]byte("hello"), "/created.txt")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_IsDir verifies directories and files are told apart
+func TestConnection_IsDir(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/dir/file.txt", []byte("hello"))
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/dir", true},
+		{"/dir/file.txt", false},
+	}
+	for _, tt := range tests {
+		got, err := conn.IsDir(tt.path)
+		if err != nil {
+			t.Errorf("IsDir(%q) failed: %v", tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("IsDir(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := conn.IsDir("/missing"); !errors.Is(err, ErrRemoteNotFound) {
+		t.Errorf("expected ErrRemoteNotFound, got: %v", err)
+	}
+}
+
+// TestConnection_WaitForFileSize verifies polling until a file grows large
+// enough, and the timeout when it never does
+func TestConnection_WaitForFileSize(t *testing.T) {
+	server := NewMockServer(t)
+	opts := server.Options()
+	opts.PollInterval = 10 * time.Millisecond
+	conn, err := (&Client{}).ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	t.Run("Returns once the file is large enough", func(t *testing.T) {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			os.WriteFile(server.localPath("/growing.log"), []byte("first batch\n"), 0o644)
+		}()
+
+		if err := conn.WaitForFileSize("/growing.log", 5, 2*time.Second); err != nil {
+			t.Errorf("WaitForFileSize failed: %v", err)
+		}
+	})
+
+	t.Run("Times out when the file stays small", func(t *testing.T) {
+		server.WriteFile(t, "/small.log", []byte("x"))
+
+		start := time.Now()
+		err := conn.WaitForFileSize("/small.log", 100, 100*time.Millisecond)
+		if !errors.Is(err, ErrWaitTimeout) {
+			t.Errorf("expected ErrWaitTimeout, got: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected timeout after ~100ms, took %v", elapsed)
+		}
+	})
+}
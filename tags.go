@@ -0,0 +1,65 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// contentTypeTag is the extended attribute UploadTagged stores the detected
+// MIME type under
+const contentTypeTag = "Content-Type"
+
+// UploadTagged uploads data to remotePath and then stores tags, plus a
+// Content-Type detected from the first 512 bytes of data, as SFTP extended
+// attributes on the file. An explicit Content-Type tag wins over the
+// detected one
+// A server that does not support extended attributes only logs a warning:
+// the upload itself has succeeded by then. Servers may also accept and
+// silently drop attributes they do not understand
+func (c *Connection) UploadTagged(data []byte, remotePath string, tags map[string]string) (err error) {
+	defer c.observe("uploadTagged", remotePath, time.Now(), &err)
+	return withReplayErr(c, func(ctx context.Context) error { return c.uploadTagged(ctx, data, remotePath, tags) })
+}
+
+func (c *Connection) uploadTagged(ctx context.Context, data []byte, remotePath string, tags map[string]string) error {
+	if err := c.upload(ctx, data, remotePath); err != nil {
+		return err
+	}
+
+	extended := []sftp.StatExtended{{ExtType: contentTypeTag, ExtData: http.DetectContentType(data)}}
+	if explicit, ok := tags[contentTypeTag]; ok {
+		extended[0].ExtData = explicit
+	}
+	for _, name := range slices.Sorted(maps.Keys(tags)) {
+		if name != contentTypeTag {
+			extended = append(extended, sftp.StatExtended{ExtType: name, ExtData: tags[name]})
+		}
+	}
+
+	err := c.client().SetExtendedData(c.resolvePath(remotePath), extended)
+	if isUnsupported(err) {
+		if logger := c.logger(); logger != nil {
+			logger.WithError(err).WithField("path", remotePath).
+				Warn("sftp: server does not support extended attributes, tags not set")
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("set tags: %w", err)
+	}
+	return nil
+}
+
+// isUnsupported reports whether err is the server refusing an operation it
+// does not implement
+func isUnsupported(err error) bool {
+	var statusErr *sftp.StatusError
+	return errors.As(err, &statusErr) && statusErr.FxCode() == sftp.ErrSSHFxOpUnsupported
+}
@@ -0,0 +1,71 @@
+package sftp
+
+import (
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// TestConnection_UploadTagged verifies tags and the detected content type
+// are stored as extended attributes
+func TestConnection_UploadTagged(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("Tags and content type are set", func(t *testing.T) {
+		err := conn.UploadTagged([]byte("<html><body>hi</body></html>"), "/page.html", map[string]string{
+			"owner": "qa",
+			"env":   "staging",
+		})
+		if err != nil {
+			t.Fatalf("UploadTagged failed: %v", err)
+		}
+
+		want := []sftp.StatExtended{
+			{ExtType: "Content-Type", ExtData: "text/html; charset=utf-8"},
+			{ExtType: "env", ExtData: "staging"},
+			{ExtType: "owner", ExtData: "qa"},
+		}
+		got := server.Xattrs("/page.html")
+		if len(got) != len(want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("attribute %d: expected %v, got %v", i, want[i], got[i])
+			}
+		}
+		if string(server.ReadFile(t, "/page.html")) != "<html><body>hi</body></html>" {
+			t.Error("unexpected file content")
+		}
+	})
+
+	t.Run("Explicit Content-Type wins", func(t *testing.T) {
+		err := conn.UploadTagged([]byte("a,b\n"), "/data.csv", map[string]string{"Content-Type": "text/csv"})
+		if err != nil {
+			t.Fatalf("UploadTagged failed: %v", err)
+		}
+		got := server.Xattrs("/data.csv")
+		if len(got) != 1 || got[0].ExtData != "text/csv" {
+			t.Errorf("expected only text/csv, got %v", got)
+		}
+	})
+
+	t.Run("Unsupported xattrs still upload", func(t *testing.T) {
+		plain := NewMockServer(t)
+		plain.DisableXattrs()
+		if err := plain.Connect(t).UploadTagged([]byte("data"), "/file.bin", map[string]string{"k": "v"}); err != nil {
+			t.Fatalf("expected no error without xattr support, got: %v", err)
+		}
+		if string(plain.ReadFile(t, "/file.bin")) != "data" {
+			t.Error("expected the file to be uploaded")
+		}
+	})
+
+	t.Run("UploadTagged returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).UploadTagged([]byte("data"), "/file.bin", nil)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// tailBlockSize is how far TailLines reads backwards per step
+const tailBlockSize = 64 * 1024
+
+// TailBytes returns the last n bytes of a remote file, or the whole file if
+// it is shorter, reading only that part
+func (c *Connection) TailBytes(remotePath string, n int64) (_ []byte, err error) {
+	defer c.observe("tailBytes", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) ([]byte, error) { return c.tailBytes(ctx, remotePath, n) })
+}
+
+func (c *Connection) tailBytes(ctx context.Context, remotePath string, n int64) ([]byte, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("invalid byte count %d", n)
+	}
+
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat remote file: %w", err)
+	}
+
+	offset := max(0, info.Size()-n)
+	data := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read remote file: %w", err)
+	}
+	return data, nil
+}
+
+// TailLines returns the last nLines lines of a remote file without their
+// line endings, reading backwards from the end in 64 KiB blocks until
+// enough lines are found. A final line without a trailing newline counts
+func (c *Connection) TailLines(remotePath string, nLines int) (_ []string, err error) {
+	defer c.observe("tailLines", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) ([]string, error) { return c.tailLines(ctx, remotePath, nLines) })
+}
+
+func (c *Connection) tailLines(ctx context.Context, remotePath string, nLines int) ([]string, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+	if nLines <= 0 {
+		return []string{}, nil
+	}
+
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat remote file: %w", err)
+	}
+
+	var tail []byte
+	for offset := info.Size(); offset > 0; {
+		start := max(0, offset-tailBlockSize)
+		block := make([]byte, offset-start)
+		if _, err := file.ReadAt(block, start); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("read remote file: %w", err)
+		}
+		tail = append(block, tail...)
+		offset = start
+
+		// More newlines than lines wanted means the first line is complete
+		if bytes.Count(bytes.TrimSuffix(tail, []byte{'\n'}), []byte{'\n'}) >= nLines {
+			break
+		}
+	}
+
+	if len(tail) == 0 {
+		return []string{}, nil
+	}
+	parts := bytes.Split(bytes.TrimSuffix(tail, []byte{'\n'}), []byte{'\n'})
+	parts = parts[max(0, len(parts)-nLines):]
+
+	lines := make([]string, len(parts))
+	for i, line := range parts {
+		lines[i] = string(bytes.TrimSuffix(line, []byte{'\r'}))
+	}
+	return lines, nil
+}
@@ -0,0 +1,105 @@
+package sftp
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestConnection_TailBytes verifies the last bytes are returned for text
+// and binary files
+func TestConnection_TailBytes(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	binary := []byte{0x00, 0xff, 0x10, 0x0a, 0x00, 0x01}
+	server.WriteFile(t, "/data.bin", binary)
+
+	t.Run("Last n bytes are returned", func(t *testing.T) {
+		got, err := conn.TailBytes("/data.bin", 3)
+		if err != nil {
+			t.Fatalf("TailBytes failed: %v", err)
+		}
+		if !bytes.Equal(got, binary[3:]) {
+			t.Errorf("expected %x, got %x", binary[3:], got)
+		}
+	})
+
+	t.Run("Short file is returned whole", func(t *testing.T) {
+		got, err := conn.TailBytes("/data.bin", 100)
+		if err != nil {
+			t.Fatalf("TailBytes failed: %v", err)
+		}
+		if !bytes.Equal(got, binary) {
+			t.Errorf("expected %x, got %x", binary, got)
+		}
+	})
+
+	t.Run("TailBytes returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).TailBytes("/data.bin", 1)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_TailLines verifies the last lines are returned with and
+// without a trailing newline and across block boundaries
+func TestConnection_TailLines(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	var large strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&large, "log line %05d\n", i)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		want    []string
+	}{
+		{"Trailing newline", "a\nb\nc\n", 2, []string{"b", "c"}},
+		{"No trailing newline", "a\nb\nc", 2, []string{"b", "c"}},
+		{"CRLF line endings", "a\r\nb\r\nc\r\n", 2, []string{"b", "c"}},
+		{"Fewer lines than requested", "a\nb\n", 5, []string{"a", "b"}},
+		{"Empty file", "", 3, []string{}},
+		{"Blank lines are kept", "a\n\n\n", 2, []string{"", ""}},
+		{"Lines across blocks", large.String(), 3, []string{"log line 19997", "log line 19998", "log line 19999"}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remotePath := fmt.Sprintf("/log-%d.txt", i)
+			server.WriteFile(t, remotePath, []byte(tt.content))
+
+			got, err := conn.TailLines(remotePath, tt.n)
+			if err != nil {
+				t.Fatalf("TailLines failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Whole large file when asking for every line", func(t *testing.T) {
+		remotePath := "/log-all.txt"
+		server.WriteFile(t, remotePath, []byte(large.String()))
+		got, err := conn.TailLines(remotePath, 20000)
+		if err != nil {
+			t.Fatalf("TailLines failed: %v", err)
+		}
+		if len(got) != 20000 || got[0] != "log line 00000" {
+			t.Errorf("expected all 20000 lines, got %d starting %q", len(got), got[0])
+		}
+	})
+
+	t.Run("TailLines returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).TailLines("/log.txt", 1)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// ErrOperationTimeout is returned when an operation takes longer than
+// ConnectionOptions.OperationTimeout
+var ErrOperationTimeout = errors.New("operation timed out")
+
+// ErrConnectTimeout is returned when a phase of connecting takes longer
+// than its timeout, e.g. ConnectionOptions.SSHAuthTimeout
+var ErrConnectTimeout = errors.New("connect timed out")
+
+// transferChunkSize is how much transfers write at a time, so an aborted
+// operation stops between chunks instead of after the whole buffer
+const transferChunkSize = 1024 * 1024
+
+// withTimeout runs fn with a context that is done once the connection's
+// OperationTimeout has passed, the VU context is done, e.g. on k6's
+// graceful stop, or withTimeout has returned. It gives up on fn as soon as
+// the context is done
+// pkg/sftp requests cannot be cancelled, so the context instead closes the
+// files fn opened with openFile and fails fn's reads and writes through
+// ctxReader and ctxWriter, which stops it after its pending request
+// If the server closed the SFTP session fn's error is returned and the
+// session reopened for later operations, see withSession and withReplay
+func withTimeout[T any](c *Connection, fn func(ctx context.Context) (T, error)) (T, error) {
+	return runOperation(c, false, fn)
+}
+
+// withReplay is withTimeout for idempotent operations, which run once more
+// on the reopened session if the server closed the SFTP session
+func withReplay[T any](c *Connection, fn func(ctx context.Context) (T, error)) (T, error) {
+	return runOperation(c, true, fn)
+}
+
+// runOperation implements withTimeout and withReplay
+func runOperation[T any](c *Connection, replay bool, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	parent := c.vuContext()
+	if err := parent.Err(); err != nil {
+		return zero, fmt.Errorf("operation aborted: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	timeout := c.opts.OperationTimeout
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w after %s", ErrOperationTimeout, timeout))
+		defer cancel()
+	} else if parent.Done() == nil {
+		return withSession(c, replay, func() (T, error) { return fn(ctx) })
+	}
+
+	type result struct {
+		val T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		val, err := withSession(c, replay, func() (T, error) { return fn(ctx) })
+		done <- result{val, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.val, r.err
+	case <-ctx.Done():
+		return zero, operationError(ctx)
+	}
+}
+
+// withTimeoutErr is withTimeout for operations that only return an error
+func withTimeoutErr(c *Connection, fn func(ctx context.Context) error) error {
+	_, err := withTimeout(c, func(ctx context.Context) (struct{}, error) { return struct{}{}, fn(ctx) })
+	return err
+}
+
+// withReplayErr is withReplay for operations that only return an error
+func withReplayErr(c *Connection, fn func(ctx context.Context) error) error {
+	_, err := withReplay(c, func(ctx context.Context) (struct{}, error) { return struct{}{}, fn(ctx) })
+	return err
+}
+
+// operationError returns why an operation's context from withTimeout is
+// done: ErrOperationTimeout, or the VU context's error as operation aborted
+func operationError(ctx context.Context) error {
+	if cause := context.Cause(ctx); errors.Is(cause, ErrOperationTimeout) {
+		return cause
+	}
+	return fmt.Errorf("operation aborted: %w", ctx.Err())
+}
+
+// vuContext returns the VU's context, or context.Background outside of a
+// VU or before k6 has set one
+func (c *Connection) vuContext() context.Context {
+	if c.vu == nil || c.vu.Context() == nil {
+		return context.Background()
+	}
+	return c.vu.Context()
+}
+
+// sleep waits for d, or returns operation aborted once the VU context is
+// done, e.g. between polls
+func (c *Connection) sleep(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	ctx := c.vuContext()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("operation aborted: %w", ctx.Err())
+	}
+}
+
+// open opens remotePath for reading, see openFile
+func (c *Connection) open(ctx context.Context, remotePath string) (*sftp.File, error) {
+	return c.openFile(ctx, remotePath, os.O_RDONLY)
+}
+
+// openFile opens remotePath like sftp.Client.OpenFile, subject to the path
+// policy, and closes the file once ctx is done, so an operation abandoned by
+// withTimeout stops using it
+func (c *Connection) openFile(ctx context.Context, remotePath string, flag int) (*sftp.File, error) {
+	client := c.client()
+	if client == nil {
+		return nil, errors.New("not connected")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := c.checkPathPolicy(remotePath); err != nil {
+		return nil, err
+	}
+
+	file, err := client.OpenFile(c.resolvePath(remotePath), flag)
+	if err != nil {
+		return nil, err
+	}
+	context.AfterFunc(ctx, func() { file.Close() })
+	return file, nil
+}
+
+// ctxReader fails Read once ctx is done, e.g. to stop sftp.File.ReadFrom
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// Size reports the bytes left in the underlying reader, or -1 if it is
+// unknown, so sftp.File.ReadFrom sizes its concurrent writes as it would
+// for the reader itself. For a file that is its size past the current
+// offset; -1 makes ReadFrom use its maximum concurrency
+func (r ctxReader) Size() int64 {
+	switch r := r.r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case interface{ Size() int64 }:
+		return r.Size()
+	case *io.LimitedReader:
+		return r.N
+	case interface {
+		Stat() (os.FileInfo, error)
+		Seek(offset int64, whence int) (int64, error)
+	}:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return max(info.Size()-offset, 0)
+	}
+	return -1
+}
+
+// ctxWriter fails Write once ctx is done, e.g. to stop sftp.File.WriteTo
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w ctxWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+// writeChunked writes data to w in chunks of transferChunkSize, stopping
+// once ctx is done
+func writeChunked(ctx context.Context, w io.Writer, data []byte) (int64, error) {
+	var written int64
+	for len(data) > 0 {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		n, err := w.Write(data[:min(len(data), transferChunkSize)])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+		data = data[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,246 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+// TestConnection_OperationTimeout verifies operations slower than
+// OperationTimeout fail with ErrOperationTimeout
+func TestConnection_OperationTimeout(t *testing.T) {
+	server := NewMockServer(t)
+	server.WriteFile(t, "/slow.txt", []byte("slow"))
+	server.SetLatency(200*time.Millisecond, 10*time.Millisecond)
+
+	opts := server.Options()
+	opts.OperationTimeout = 100 * time.Millisecond
+	conn, err := (&Client{}).ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	t.Run("Slow operations time out", func(t *testing.T) {
+		start := time.Now()
+		if _, err := conn.Stat("/slow.txt"); !errors.Is(err, ErrOperationTimeout) {
+			t.Errorf("expected ErrOperationTimeout from Stat, got: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 180*time.Millisecond {
+			t.Errorf("expected Stat to give up after about 100ms, took %v", elapsed)
+		}
+		if err := conn.Upload([]byte("data"), "/upload.txt"); !errors.Is(err, ErrOperationTimeout) {
+			t.Errorf("expected ErrOperationTimeout from Upload, got: %v", err)
+		}
+		if _, err := conn.Ls("/"); !errors.Is(err, ErrOperationTimeout) {
+			t.Errorf("expected ErrOperationTimeout from Ls, got: %v", err)
+		}
+	})
+
+	t.Run("Operations within the timeout succeed", func(t *testing.T) {
+		server.SetLatency(10*time.Millisecond, 0)
+		// A new connection, since the abandoned requests above are still
+		// queued on the first
+		fast, err := (&Client{}).ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer fast.Close()
+		if _, err := fast.Stat("/slow.txt"); err != nil {
+			t.Errorf("expected Stat to succeed, got: %v", err)
+		}
+	})
+
+	t.Run("Zero timeout waits for slow operations", func(t *testing.T) {
+		server.SetLatency(150*time.Millisecond, 0)
+		slow := server.Connect(t)
+		if _, err := slow.Stat("/slow.txt"); err != nil {
+			t.Errorf("expected Stat to succeed without a timeout, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_VUContext verifies operations return the VU context's
+// error once it is done instead of waiting for the server
+func TestConnection_VUContext(t *testing.T) {
+	server := NewMockServer(t)
+	server.WriteFile(t, "/data.txt", []byte("data"))
+
+	connect := func(t *testing.T, ctx context.Context) *Connection {
+		t.Helper()
+		client := &Client{vu: &modulestest.VU{CtxField: ctx}}
+		conn, err := client.ConnectWithOptions(server.Options())
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	t.Run("Deadline aborts a transfer", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		conn := connect(t, ctx)
+		server.SetLatency(300*time.Millisecond, 0)
+		defer server.SetLatency(0, 0)
+
+		start := time.Now()
+		err := conn.Upload(bytes.Repeat([]byte("x"), 1<<20), "/big.bin")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+			t.Errorf("expected Upload to give up at the deadline, took %v", elapsed)
+		}
+	})
+
+	t.Run("Cancellation aborts a transfer", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		conn := connect(t, ctx)
+		server.SetLatency(300*time.Millisecond, 0)
+		defer server.SetLatency(0, 0)
+
+		time.AfterFunc(50*time.Millisecond, cancel)
+		localPath := filepath.Join(t.TempDir(), "data.txt")
+		if err := conn.Download("/data.txt", localPath); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got: %v", err)
+		}
+	})
+
+	t.Run("Done context fails without a request", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		conn := connect(t, ctx)
+		cancel()
+
+		if _, err := conn.Stat("/data.txt"); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got: %v", err)
+		}
+	})
+
+	t.Run("Live context lets operations finish", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		conn := connect(t, ctx)
+
+		if _, err := conn.Stat("/data.txt"); err != nil {
+			t.Errorf("expected Stat to succeed, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_OperationTimeoutCoverage verifies OperationTimeout bounds
+// every operation, not just the core ones, and that a timed out transfer
+// stops instead of writing on in the background
+func TestConnection_OperationTimeoutCoverage(t *testing.T) {
+	server := NewMockServer(t)
+	server.WriteFile(t, "/data.csv", []byte("a,b\n1,2\n"))
+	server.WriteFile(t, "/dir/report-1.txt", []byte("report"))
+
+	opts := server.Options()
+	opts.OperationTimeout = 100 * time.Millisecond
+	conn, err := (&Client{}).ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	t.Run("Every operation times out", func(t *testing.T) {
+		server.SetLatency(300*time.Millisecond, 0)
+		defer server.SetLatency(0, 0)
+
+		ops := map[string]func() error{
+			"CountLines": func() error { _, err := conn.CountLines("/data.csv"); return err },
+			"Grep":       func() error { _, err := conn.Grep("/data.csv", "1"); return err },
+			"TailLines":  func() error { _, err := conn.TailLines("/data.csv", 1); return err },
+			"HeadBytes":  func() error { _, err := conn.HeadBytes("/data.csv", 1); return err },
+			"HashFile":   func() error { _, err := conn.HashFile("/data.csv", "sha256"); return err },
+			"ValidateCSV": func() error {
+				_, err := conn.ValidateCSV("/data.csv", []string{"a", "b"})
+				return err
+			},
+			"UploadResume": func() error { return conn.UploadResume([]byte("a,b\n1,2\n3,4\n"), "/data.csv") },
+			"RenamePattern": func() error {
+				_, err := conn.RenamePattern("/dir", `report-(\d+)`, "r-{{index .Groups 0}}")
+				return err
+			},
+			"RemoveAll": func() error { _, err := conn.RemoveAll("/dir"); return err },
+		}
+		for name, op := range ops {
+			start := time.Now()
+			if err := op(); !errors.Is(err, ErrOperationTimeout) {
+				t.Errorf("expected ErrOperationTimeout from %s, got: %v", name, err)
+			}
+			if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+				t.Errorf("expected %s to give up after about 100ms, took %v", name, elapsed)
+			}
+		}
+	})
+
+	t.Run("Timed out upload stops writing", func(t *testing.T) {
+		server := NewMockServer(t)
+		server.WriteLatency = 20 * time.Millisecond
+		opts := server.Options()
+		opts.OperationTimeout = 100 * time.Millisecond
+		conn, err := (&Client{}).ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		data := bytes.Repeat([]byte("x"), 32*transferChunkSize)
+		if err := conn.Upload(data, "/big.bin"); !errors.Is(err, ErrOperationTimeout) {
+			t.Fatalf("expected ErrOperationTimeout, got: %v", err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for server.OpenHandles() > 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("expected the abandoned upload to close its file")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if got := len(server.ReadFile(t, "/big.bin")); got >= len(data) {
+			t.Errorf("expected the upload to stop early, %d of %d bytes were written", got, len(data))
+		}
+	})
+}
+
+// TestCtxReader_Size verifies the bytes left in the wrapped reader are
+// reported, so pkg/sftp can size its concurrent writes
+func TestCtxReader_Size(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "size.bin")
+	if err := os.WriteFile(localPath, make([]byte, 100), 0o644); err != nil {
+		t.Fatalf("write local file: %v", err)
+	}
+	f, err := os.Open(localPath)
+	if err != nil {
+		t.Fatalf("open local file: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(30, io.SeekStart); err != nil {
+		t.Fatalf("seek local file: %v", err)
+	}
+
+	ctx := context.Background()
+	for name, tc := range map[string]struct {
+		r    io.Reader
+		want int64
+	}{
+		"Buffer":        {bytes.NewBufferString("hello"), 5},
+		"LimitedReader": {io.LimitReader(strings.NewReader("hello"), 3), 3},
+		"File":          {f, 70},
+		"Unknown":       {io.MultiReader(strings.NewReader("hello")), -1},
+	} {
+		if got := (ctxReader{ctx, tc.r}).Size(); got != tc.want {
+			t.Errorf("%s: expected size %d, got %d", name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Operation types a Transaction can stage
+const (
+	OpUpload = "upload"
+	OpRename = "rename"
+)
+
+// Operation is one step of a Transaction
+type Operation struct {
+	// Type is OpUpload or OpRename
+	Type string `js:"type"`
+	// Path is the upload destination or the file to rename
+	Path string `js:"path"`
+	// Target is the new name of a renamed file
+	Target string `js:"target"`
+	// Data is the content to upload
+	Data []byte `js:"data"`
+}
+
+// UploadOp returns an Operation uploading data to dstPath
+func UploadOp(data []byte, dstPath string) Operation {
+	return Operation{Type: OpUpload, Path: dstPath, Data: data}
+}
+
+// RenameOp returns an Operation renaming oldPath to newPath
+func RenameOp(oldPath, newPath string) Operation {
+	return Operation{Type: OpRename, Path: oldPath, Target: newPath}
+}
+
+// String describes the operation in errors, e.g. "rename /a -> /b"
+func (op Operation) String() string {
+	if op.Type == OpRename {
+		return fmt.Sprintf("%s %s -> %s", op.Type, op.Path, op.Target)
+	}
+	return fmt.Sprintf("%s %s", op.Type, op.Path)
+}
+
+// TransactionError is returned by Transaction.Commit when a staged
+// operation fails. The operations before it have been rolled back, except
+// those listed in RollbackErrors
+type TransactionError struct {
+	// Index is the position of the failed operation in the batch
+	Index int
+	Op    Operation
+	Err   error
+	// RollbackErrors holds the undo steps that failed too, most recent
+	// operation first. Empty if everything was rolled back
+	RollbackErrors []error
+}
+
+func (e *TransactionError) Error() string {
+	msg := fmt.Sprintf("operation %d (%s) failed: %v", e.Index, e.Op, e.Err)
+	if len(e.RollbackErrors) == 0 {
+		return msg + "; rolled back"
+	}
+	details := make([]string, len(e.RollbackErrors))
+	for i, err := range e.RollbackErrors {
+		details[i] = err.Error()
+	}
+	return msg + "; rollback failed: " + strings.Join(details, ", ")
+}
+
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
+
+// Transaction stages uploads and renames and applies them all or nothing,
+// e.g. to test deployment workflows that must not leave half an update
+// Create one with Connection.BeginBatch
+type Transaction struct {
+	conn      *Connection
+	ops       []Operation
+	committed bool
+}
+
+// BeginBatch starts an empty Transaction on the connection
+func (c *Connection) BeginBatch() *Transaction {
+	return &Transaction{conn: c}
+}
+
+// Stage appends op to the batch
+func (t *Transaction) Stage(op Operation) *Transaction {
+	t.ops = append(t.ops, op)
+	return t
+}
+
+// Commit applies the staged operations in order. If one fails, the ones
+// before it are undone in reverse order: a rename is renamed back and an
+// upload removed, so an upload that replaced an existing file loses that
+// file on rollback. Returns a *TransactionError listing any undo step that
+// failed as well. A transaction can be committed once
+func (t *Transaction) Commit() (err error) {
+	c := t.conn
+	var failedPath string
+	start := time.Now()
+	defer func() { c.observe("commit", failedPath, start, &err) }()
+
+	if c.client() == nil {
+		return errors.New("not connected")
+	}
+	if t.committed {
+		return errors.New("transaction already committed")
+	}
+	t.committed = true
+
+	for i, op := range t.ops {
+		if err := withTimeoutErr(c, func(ctx context.Context) error { return t.apply(ctx, op) }); err != nil {
+			failedPath = op.Path
+			return &TransactionError{Index: i, Op: op, Err: err, RollbackErrors: t.rollback(t.ops[:i])}
+		}
+	}
+	return nil
+}
+
+// apply runs a single operation
+func (t *Transaction) apply(ctx context.Context, op Operation) error {
+	switch op.Type {
+	case OpUpload:
+		return t.conn.upload(ctx, op.Data, op.Path)
+	case OpRename:
+		if err := t.conn.client().Rename(t.conn.resolvePath(op.Path), t.conn.resolvePath(op.Target)); err != nil {
+			return fmt.Errorf("rename: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown operation type %q", op.Type)
+	}
+}
+
+// rollback undoes completed operations, most recent first, and returns the
+// undo steps that failed
+func (t *Transaction) rollback(done []Operation) []error {
+	var errs []error
+	for i := len(done) - 1; i >= 0; i-- {
+		op := done[i]
+		err := withTimeoutErr(t.conn, func(context.Context) error {
+			switch op.Type {
+			case OpUpload:
+				return t.conn.client().Remove(t.conn.resolvePath(op.Path))
+			case OpRename:
+				return t.conn.client().Rename(t.conn.resolvePath(op.Target), t.conn.resolvePath(op.Path))
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("undo %s: %w", op, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,96 @@
+package sftp
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestTransaction verifies a batch is applied in full or rolled back
+func TestTransaction(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/deploy/current.txt", []byte("v1"))
+
+	exists := func(remotePath string) bool {
+		_, err := os.Stat(server.localPath(remotePath))
+		return err == nil
+	}
+
+	t.Run("Successful batch applies every operation", func(t *testing.T) {
+		err := conn.BeginBatch().
+			Stage(UploadOp([]byte("v2"), "/deploy/next.txt")).
+			Stage(RenameOp("/deploy/current.txt", "/deploy/previous.txt")).
+			Stage(RenameOp("/deploy/next.txt", "/deploy/current.txt")).
+			Commit()
+		if err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/deploy/current.txt")); got != "v2" {
+			t.Errorf("expected v2, got %q", got)
+		}
+		if got := string(server.ReadFile(t, "/deploy/previous.txt")); got != "v1" {
+			t.Errorf("expected v1 kept as previous, got %q", got)
+		}
+	})
+
+	t.Run("Failed operation rolls back the batch", func(t *testing.T) {
+		err := conn.BeginBatch().
+			Stage(UploadOp([]byte("v3"), "/deploy/v3.txt")).
+			Stage(RenameOp("/deploy/current.txt", "/deploy/old.txt")).
+			Stage(RenameOp("/deploy/missing.txt", "/deploy/current.txt")).
+			Commit()
+
+		var txErr *TransactionError
+		if !errors.As(err, &txErr) {
+			t.Fatalf("expected *TransactionError, got: %v", err)
+		}
+		if txErr.Index != 2 || len(txErr.RollbackErrors) != 0 {
+			t.Errorf("expected operation 2 to fail with a clean rollback, got %+v", txErr)
+		}
+		if exists("/deploy/v3.txt") || exists("/deploy/old.txt") {
+			t.Error("expected the upload and rename to be undone")
+		}
+		if got := string(server.ReadFile(t, "/deploy/current.txt")); got != "v2" {
+			t.Errorf("expected current.txt restored, got %q", got)
+		}
+	})
+
+	t.Run("Failed undo steps are listed", func(t *testing.T) {
+		// pkg/sftp retries a failed Remove as Rmdir, so fail both
+		server.FailCmds("/deploy/stuck.txt", 2)
+		err := conn.BeginBatch().
+			Stage(UploadOp([]byte("x"), "/deploy/stuck.txt")).
+			Stage(Operation{Type: "delete", Path: "/deploy/current.txt"}).
+			Commit()
+
+		var txErr *TransactionError
+		if !errors.As(err, &txErr) {
+			t.Fatalf("expected *TransactionError, got: %v", err)
+		}
+		if len(txErr.RollbackErrors) != 1 || !strings.Contains(txErr.RollbackErrors[0].Error(), "undo upload /deploy/stuck.txt") {
+			t.Errorf("expected the failed removal to be listed, got %v", txErr.RollbackErrors)
+		}
+		if !strings.Contains(err.Error(), `unknown operation type "delete"`) || !strings.Contains(err.Error(), "rollback failed") {
+			t.Errorf("unexpected error message %q", err)
+		}
+	})
+
+	t.Run("Batch can be committed once", func(t *testing.T) {
+		tx := conn.BeginBatch()
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		if err := tx.Commit(); err == nil {
+			t.Error("expected error for a second Commit")
+		}
+	})
+
+	t.Run("Commit returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).BeginBatch().Commit()
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,327 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// downloadManyConcurrency bounds the files DownloadManyBytes reads at once
+const downloadManyConcurrency = 8
+
+// UploadResume uploads srcbytes to dstPath, continuing a previous partial
+// upload instead of starting over
+//   - remote file missing: srcbytes is uploaded in full
+//   - remote file shorter: the remainder of srcbytes is written from the
+//     remote size onwards; the existing prefix is assumed to match
+//   - remote file the same size: the upload is skipped
+//   - remote file larger: it is truncated and srcbytes re-uploaded
+//
+// The operation is not atomic: readers may observe the file while the
+// remainder is written, and a failure leaves a partial file behind that a
+// later UploadResume call continues from
+func (c *Connection) UploadResume(srcbytes []byte, dstPath string) (err error) {
+	defer c.observe("uploadResume", dstPath, time.Now(), &err)
+	return withReplayErr(c, func(ctx context.Context) error { return c.uploadResume(ctx, srcbytes, dstPath) })
+}
+
+func (c *Connection) uploadResume(ctx context.Context, srcbytes []byte, dstPath string) error {
+	if c.client() == nil {
+		return errors.New("not connected")
+	}
+	if err := c.checkPathPolicy(dstPath); err != nil {
+		return err
+	}
+
+	info, err := c.client().Stat(c.resolvePath(dstPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return c.upload(ctx, srcbytes, dstPath)
+	}
+	if err != nil {
+		return fmt.Errorf("stat remote file: %w", err)
+	}
+
+	offset := info.Size()
+	switch {
+	case offset == int64(len(srcbytes)):
+		return nil
+	case offset > int64(len(srcbytes)):
+		return c.upload(ctx, srcbytes, dstPath)
+	}
+
+	file, err := c.openFile(ctx, dstPath, os.O_WRONLY)
+	if err != nil {
+		return fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := writeChunked(ctx, io.NewOffsetWriter(file, offset), srcbytes[offset:]); err != nil {
+		return fmt.Errorf("write to remote file: %w", err)
+	}
+
+	return nil
+}
+
+// UploadIfOlderThan uploads srcbytes to dstPath only if the remote file is
+// missing or was last modified more than maxAge ago, e.g. to refresh
+// fixture files on a TTL. Returns uploaded=false when the upload was skipped
+func (c *Connection) UploadIfOlderThan(srcbytes []byte, dstPath string, maxAge time.Duration) (uploaded bool, err error) {
+	defer c.observe("uploadIfOlderThan", dstPath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (bool, error) { return c.uploadIfOlderThan(ctx, srcbytes, dstPath, maxAge) })
+}
+
+func (c *Connection) uploadIfOlderThan(ctx context.Context, srcbytes []byte, dstPath string, maxAge time.Duration) (bool, error) {
+	if c.client() == nil {
+		return false, errors.New("not connected")
+	}
+
+	info, err := c.client().Stat(c.resolvePath(dstPath))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("stat remote file: %w", err)
+	}
+	if err == nil && !info.ModTime().Before(time.Now().Add(-maxAge)) {
+		return false, nil
+	}
+
+	if err := c.upload(ctx, srcbytes, dstPath); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DownloadStream reads the remote file in chunkSize chunks in the
+// background and sends each chunk on the returned data channel, so large
+// files can be processed without buffering them whole
+// Both channels are closed once the file has been read; a failure is sent
+// on the error channel before they close. The data channel must be drained,
+// otherwise the reading goroutine blocks until the VU context is done and
+// then sends operation aborted
+// OperationTimeout bounds the open and each chunk's read, not the stream
+func (c *Connection) DownloadStream(remotePath string, chunkSize int) (<-chan []byte, <-chan error) {
+	data := make(chan []byte)
+	errs := make(chan error, 1)
+	start := time.Now()
+
+	fail := func(err error) (<-chan []byte, <-chan error) {
+		c.observe("downloadStream", remotePath, start, &err)
+		errs <- err
+		close(data)
+		close(errs)
+		return data, errs
+	}
+
+	if c.client() == nil {
+		return fail(errors.New("not connected"))
+	}
+	if chunkSize <= 0 {
+		return fail(fmt.Errorf("invalid chunk size %d", chunkSize))
+	}
+
+	// The file outlives this call, so it is tied to the VU context rather
+	// than to an operation's
+	vuCtx := c.vuContext()
+	file, err := withReplay(c, func(context.Context) (*sftp.File, error) { return c.open(vuCtx, remotePath) })
+	if err != nil {
+		return fail(fmt.Errorf("open remote file: %w", err))
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(data)
+
+		err := c.sendChunks(vuCtx, file, chunkSize, data)
+		file.Close()
+		c.observe("downloadStream", remotePath, start, &err)
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return data, errs
+}
+
+// sendChunks reads file in chunkSize chunks for DownloadStream and sends
+// them on data until the end of the file or until vuCtx is done
+func (c *Connection) sendChunks(vuCtx context.Context, file *sftp.File, chunkSize int, data chan<- []byte) error {
+	for {
+		buf := make([]byte, chunkSize)
+		n, err := withTimeout(c, func(ctx context.Context) (int, error) { return io.ReadFull(ctxReader{ctx, file}, buf) })
+		if n > 0 {
+			select {
+			case data <- buf[:n]:
+			case <-vuCtx.Done():
+				return fmt.Errorf("operation aborted: %w", vuCtx.Err())
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read remote file: %w", err)
+		}
+	}
+}
+
+// UploadStream writes the chunks received on the channel sequentially to
+// dstPath and closes the remote file once the channel is closed
+// Paired with DownloadStream on another connection it streams a file from
+// one server to another without buffering it whole. On a write failure the
+// remaining chunks are drained so the producer does not block
+// OperationTimeout bounds the open and each chunk's write, not the stream
+func (c *Connection) UploadStream(dstPath string, chunks <-chan []byte) (err error) {
+	defer c.observe("uploadStream", dstPath, time.Now(), &err)
+
+	if c.client() == nil {
+		return errors.New("not connected")
+	}
+
+	vuCtx := c.vuContext()
+	file, err := withReplay(c, func(context.Context) (*sftp.File, error) {
+		return c.openFile(vuCtx, dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+	})
+	if err != nil {
+		return fmt.Errorf("open remote file: %w", err)
+	}
+
+	for chunk := range chunks {
+		if err := withTimeoutErr(c, func(ctx context.Context) error {
+			_, err := writeChunked(ctx, file, chunk)
+			return err
+		}); err != nil {
+			file.Close()
+			for range chunks {
+			}
+			return fmt.Errorf("write to remote file: %w", err)
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close remote file: %w", err)
+	}
+
+	return nil
+}
+
+// UploadOpenFile copies an already open local file to dstPath without
+// reading it into memory first, returning the number of bytes written
+// Copying starts at the file's current offset. The caller owns f and is
+// responsible for closing it
+func (c *Connection) UploadOpenFile(f *os.File, dstPath string) (n int64, err error) {
+	var localPath string
+	if f != nil {
+		localPath = f.Name()
+	}
+	defer c.observeTransfer("uploadOpenFile", dstPath, localPath, time.Now(), &n, &err)
+	if f == nil {
+		return 0, errors.New("no local file")
+	}
+	return withTimeout(c, func(ctx context.Context) (int64, error) { return c.uploadFrom(ctx, f, dstPath) })
+}
+
+// uploadFrom copies r to dstPath, replacing any existing file
+func (c *Connection) uploadFrom(ctx context.Context, r io.Reader, dstPath string) (int64, error) {
+	file, err := c.openFile(ctx, dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+	if err != nil {
+		return 0, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	n, err := io.Copy(file, ctxReader{ctx, r})
+	if err != nil {
+		return n, fmt.Errorf("copy file: %w", err)
+	}
+
+	return n, nil
+}
+
+// UploadPreserveTimes uploads the local file at srcPath to dstPath and then
+// sets the remote access and modification times to those of the local file
+func (c *Connection) UploadPreserveTimes(srcPath, dstPath string) (err error) {
+	var n int64
+	defer c.observeTransfer("uploadPreserveTimes", dstPath, srcPath, time.Now(), &n, &err)
+	n, err = withReplay(c, func(ctx context.Context) (int64, error) { return c.uploadPreserveTimes(ctx, srcPath, dstPath) })
+	return err
+}
+
+// uploadPreserveTimes uploads srcPath to dstPath, returning the bytes copied
+func (c *Connection) uploadPreserveTimes(ctx context.Context, srcPath, dstPath string) (int64, error) {
+	if c.client() == nil {
+		return 0, errors.New("not connected")
+	}
+
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return 0, fmt.Errorf("open local file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("stat local file: %w", err)
+	}
+
+	n, err := c.uploadFrom(ctx, f, dstPath)
+	if err != nil {
+		return n, err
+	}
+
+	if err := c.client().Chtimes(c.resolvePath(dstPath), accessTime(info), info.ModTime()); err != nil {
+		return n, fmt.Errorf("set remote times: %w", err)
+	}
+
+	return n, nil
+}
+
+// DownloadManyBytes reads several remote files into memory concurrently,
+// at most downloadManyConcurrency at a time, and maps each path to its
+// content, e.g. to compare produced artifacts with expected values
+// Failures are joined into the error and their paths left out of the result
+func (c *Connection) DownloadManyBytes(paths []string) (_ map[string][]byte, err error) {
+	defer c.observe("downloadManyBytes", "", time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (map[string][]byte, error) { return c.downloadManyBytes(ctx, paths) })
+}
+
+func (c *Connection) downloadManyBytes(ctx context.Context, paths []string) (map[string][]byte, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string][]byte, len(paths))
+		errs    []error
+		sem     = make(chan struct{}, downloadManyConcurrency)
+	)
+
+	for _, p := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			data, err := c.readAll(ctx, p)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", p, err))
+				return
+			}
+			results[p] = data
+		}()
+	}
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
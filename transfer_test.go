@@ -0,0 +1,537 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+// TestConnection_UploadResume verifies partial uploads are continued,
+// complete ones skipped and oversized ones rewritten
+func TestConnection_UploadResume(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	data := []byte("0123456789")
+
+	t.Run("Not connected returns error", func(t *testing.T) {
+		err := (&Connection{}).UploadResume(data, "/resume.txt")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+
+	t.Run("Missing remote file is uploaded in full", func(t *testing.T) {
+		if err := conn.UploadResume(data, "/new.txt"); err != nil {
+			t.Fatalf("UploadResume failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/new.txt")); got != string(data) {
+			t.Errorf("remote content mismatch: got %q", got)
+		}
+	})
+
+	t.Run("Shorter remote file is continued", func(t *testing.T) {
+		// A marker in the prefix proves the prefix is not rewritten
+		server.WriteFile(t, "/partial.txt", []byte("0X234"))
+		if err := conn.UploadResume(data, "/partial.txt"); err != nil {
+			t.Fatalf("UploadResume failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/partial.txt")); got != "0X23456789" {
+			t.Errorf("expected only the remainder to be appended, got %q", got)
+		}
+	})
+
+	t.Run("Same-size remote file is skipped", func(t *testing.T) {
+		server.WriteFile(t, "/same.txt", []byte("abcdefghij"))
+		if err := conn.UploadResume(data, "/same.txt"); err != nil {
+			t.Fatalf("UploadResume failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/same.txt")); got != "abcdefghij" {
+			t.Errorf("expected upload to be skipped, got %q", got)
+		}
+	})
+
+	t.Run("Larger remote file is rewritten", func(t *testing.T) {
+		server.WriteFile(t, "/larger.txt", []byte("this remote file is too long"))
+		if err := conn.UploadResume(data, "/larger.txt"); err != nil {
+			t.Fatalf("UploadResume failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/larger.txt")); got != string(data) {
+			t.Errorf("expected file to be rewritten, got %q", got)
+		}
+	})
+}
+
+// TestConnection_UploadIfOlderThan verifies only missing or stale files are
+// uploaded
+func TestConnection_UploadIfOlderThan(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("Missing file is uploaded", func(t *testing.T) {
+		uploaded, err := conn.UploadIfOlderThan([]byte("fresh"), "/fixture.txt", time.Hour)
+		if err != nil {
+			t.Fatalf("UploadIfOlderThan failed: %v", err)
+		}
+		if !uploaded {
+			t.Error("expected missing file to be uploaded")
+		}
+		if got := string(server.ReadFile(t, "/fixture.txt")); got != "fresh" {
+			t.Errorf("expected 'fresh', got %q", got)
+		}
+	})
+
+	t.Run("Recent file is skipped", func(t *testing.T) {
+		uploaded, err := conn.UploadIfOlderThan([]byte("newer"), "/fixture.txt", time.Hour)
+		if err != nil {
+			t.Fatalf("UploadIfOlderThan failed: %v", err)
+		}
+		if uploaded {
+			t.Error("expected recent file to be skipped")
+		}
+		if got := string(server.ReadFile(t, "/fixture.txt")); got != "fresh" {
+			t.Errorf("expected content to stay 'fresh', got %q", got)
+		}
+	})
+
+	t.Run("Stale file is replaced", func(t *testing.T) {
+		old := time.Now().Add(-2 * time.Hour)
+		if err := os.Chtimes(server.localPath("/fixture.txt"), old, old); err != nil {
+			t.Fatalf("age remote file: %v", err)
+		}
+
+		uploaded, err := conn.UploadIfOlderThan([]byte("newer"), "/fixture.txt", time.Hour)
+		if err != nil {
+			t.Fatalf("UploadIfOlderThan failed: %v", err)
+		}
+		if !uploaded {
+			t.Error("expected stale file to be uploaded")
+		}
+		if got := string(server.ReadFile(t, "/fixture.txt")); got != "newer" {
+			t.Errorf("expected 'newer', got %q", got)
+		}
+	})
+
+	t.Run("UploadIfOlderThan returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).UploadIfOlderThan([]byte("data"), "/fixture.txt", time.Hour)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_DownloadStream verifies the remote file arrives in order in
+// chunks of the requested size
+func TestConnection_DownloadStream(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	server.WriteFile(t, "/stream.bin", content)
+
+	t.Run("Chunks reassemble the file", func(t *testing.T) {
+		data, errs := conn.DownloadStream("/stream.bin", 4096)
+
+		var got []byte
+		for chunk := range data {
+			if len(chunk) > 4096 {
+				t.Errorf("chunk of %d bytes exceeds chunk size", len(chunk))
+			}
+			got = append(got, chunk...)
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("DownloadStream failed: %v", err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("streamed content mismatch: got %d bytes, want %d", len(got), len(content))
+		}
+	})
+
+	t.Run("Missing file reports error", func(t *testing.T) {
+		data, errs := conn.DownloadStream("/missing.bin", 4096)
+		for range data {
+			t.Error("expected no data for missing file")
+		}
+		if err := <-errs; err == nil {
+			t.Error("expected error for missing file, got nil")
+		}
+	})
+
+	t.Run("Invalid chunk size reports error", func(t *testing.T) {
+		data, errs := conn.DownloadStream("/stream.bin", 0)
+		for range data {
+		}
+		if err := <-errs; err == nil {
+			t.Error("expected error for chunk size 0, got nil")
+		}
+	})
+
+	t.Run("Ended VU context reports error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		vuConn, err := (&Client{vu: &modulestest.VU{CtxField: ctx}}).ConnectWithOptions(server.Options())
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer vuConn.Close()
+
+		data, errs := vuConn.DownloadStream("/stream.bin", 1024)
+		<-data
+		// Let the reading goroutine block on sending the next chunk, then
+		// see the context end before draining
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		time.Sleep(50 * time.Millisecond)
+		for range data {
+		}
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got: %v", err)
+		}
+	})
+
+	t.Run("Not connected reports error", func(t *testing.T) {
+		data, errs := (&Connection{}).DownloadStream("/stream.bin", 4096)
+		for range data {
+		}
+		if err := <-errs; err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_DownloadStream_NoGoroutineLeak verifies the reader
+// goroutine exits once a stream has been consumed
+func TestConnection_DownloadStream_NoGoroutineLeak(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/stream.bin", bytes.Repeat([]byte("x"), 64*1024))
+
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		data, errs := conn.DownloadStream("/stream.bin", 1024)
+		for range data {
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("DownloadStream failed: %v", err)
+		}
+	}
+
+	waitForGoroutines(t, baseline)
+}
+
+// TestConnection_UploadStream verifies chunks are written in order and that
+// DownloadStream and UploadStream chain between two servers
+func TestConnection_UploadStream(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("Chunks are written sequentially", func(t *testing.T) {
+		chunks := make(chan []byte)
+		go func() {
+			defer close(chunks)
+			for _, part := range []string{"first ", "second ", "third"} {
+				chunks <- []byte(part)
+			}
+		}()
+
+		if err := conn.UploadStream("/chunks.txt", chunks); err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/chunks.txt")); got != "first second third" {
+			t.Errorf("remote content mismatch: got %q", got)
+		}
+	})
+
+	t.Run("Remote-to-remote transfer", func(t *testing.T) {
+		source := NewMockServer(t)
+		content := bytes.Repeat([]byte("stream me "), 5000)
+		source.WriteFile(t, "/source.bin", content)
+
+		data, errs := source.Connect(t).DownloadStream("/source.bin", 8192)
+		if err := conn.UploadStream("/copy.bin", data); err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("DownloadStream failed: %v", err)
+		}
+		if got := server.ReadFile(t, "/copy.bin"); !bytes.Equal(got, content) {
+			t.Errorf("copied content mismatch: got %d bytes, want %d", len(got), len(content))
+		}
+	})
+
+	t.Run("Not connected returns error", func(t *testing.T) {
+		chunks := make(chan []byte)
+		close(chunks)
+		err := (&Connection{}).UploadStream("/chunks.txt", chunks)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_UploadOpenFile verifies an open local file is copied in
+// full and left open for the caller
+func TestConnection_UploadOpenFile(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	content := bytes.Repeat([]byte("large file "), 100000)
+	localPath := filepath.Join(t.TempDir(), "large.bin")
+	if err := os.WriteFile(localPath, content, 0o644); err != nil {
+		t.Fatalf("write local file: %v", err)
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		t.Fatalf("open local file: %v", err)
+	}
+	defer f.Close()
+
+	n, err := conn.UploadOpenFile(f, "/large.bin")
+	if err != nil {
+		t.Fatalf("UploadOpenFile failed: %v", err)
+	}
+	if n != int64(len(content)) {
+		t.Errorf("expected %d bytes written, got %d", len(content), n)
+	}
+	if got := server.ReadFile(t, "/large.bin"); !bytes.Equal(got, content) {
+		t.Errorf("remote content mismatch: got %d bytes, want %d", len(got), len(content))
+	}
+
+	if _, err := f.Stat(); err != nil {
+		t.Errorf("expected local file to stay open, got: %v", err)
+	}
+
+	if _, err := f.Seek(int64(len(content)-5), io.SeekStart); err != nil {
+		t.Fatalf("seek local file: %v", err)
+	}
+	if n, err := conn.UploadOpenFile(f, "/tail.bin"); err != nil || n != 5 {
+		t.Errorf("expected 5 bytes from the current offset, got %d and error %v", n, err)
+	}
+	if got := server.ReadFile(t, "/tail.bin"); !bytes.Equal(got, content[len(content)-5:]) {
+		t.Errorf("expected the file's tail, got %q", got)
+	}
+
+	if _, err := conn.UploadOpenFile(nil, "/nil.bin"); err == nil {
+		t.Error("expected an error for a nil file")
+	}
+}
+
+// waitForGoroutines fails the test if the goroutine count does not return
+// to baseline shortly
+func waitForGoroutines(t *testing.T, baseline int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutine leak: %d goroutines running, baseline %d", runtime.NumGoroutine(), baseline)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestConnection_UploadPreserveTimes verifies the local atime and mtime are
+// applied to the uploaded file
+func TestConnection_UploadPreserveTimes(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	localPath := filepath.Join(t.TempDir(), "backup.tar")
+	if err := os.WriteFile(localPath, []byte("archive"), 0o644); err != nil {
+		t.Fatalf("write local file: %v", err)
+	}
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	if err := os.Chtimes(localPath, atime, mtime); err != nil {
+		t.Fatalf("set local times: %v", err)
+	}
+
+	t.Run("Times are preserved", func(t *testing.T) {
+		if err := conn.UploadPreserveTimes(localPath, "/backup.tar"); err != nil {
+			t.Fatalf("UploadPreserveTimes failed: %v", err)
+		}
+		// Stat before reading the content, which would update the atime
+		info, err := os.Stat(server.localPath("/backup.tar"))
+		if err != nil {
+			t.Fatalf("stat remote file: %v", err)
+		}
+		if got := server.ReadFile(t, "/backup.tar"); string(got) != "archive" {
+			t.Errorf("unexpected remote content %q", got)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("expected mtime %v, got %v", mtime, info.ModTime())
+		}
+		if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+			if got := accessTime(info); !got.Equal(atime) {
+				t.Errorf("expected atime %v, got %v", atime, got)
+			}
+		}
+	})
+
+	t.Run("Missing local file returns error", func(t *testing.T) {
+		err := conn.UploadPreserveTimes(filepath.Join(t.TempDir(), "missing"), "/missing")
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected os.ErrNotExist, got: %v", err)
+		}
+	})
+
+	t.Run("UploadPreserveTimes returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).UploadPreserveTimes(localPath, "/backup.tar")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_DownloadManyBytes verifies every path is read into the
+// result and failures are joined
+func TestConnection_DownloadManyBytes(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	paths := make([]string, 20)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/artifacts/%02d.bin", i)
+		server.WriteFile(t, paths[i], bytes.Repeat([]byte{byte(i)}, i))
+	}
+
+	t.Run("All files are downloaded", func(t *testing.T) {
+		results, err := conn.DownloadManyBytes(paths)
+		if err != nil {
+			t.Fatalf("DownloadManyBytes failed: %v", err)
+		}
+		if len(results) != len(paths) {
+			t.Fatalf("expected %d results, got %d", len(paths), len(results))
+		}
+		for i, p := range paths {
+			if !bytes.Equal(results[p], bytes.Repeat([]byte{byte(i)}, i)) {
+				t.Errorf("unexpected content for %s: %v", p, results[p])
+			}
+		}
+	})
+
+	t.Run("Failures are joined and left out", func(t *testing.T) {
+		results, err := conn.DownloadManyBytes([]string{paths[1], "/missing.bin"})
+		if err == nil || !strings.Contains(err.Error(), "/missing.bin: ") {
+			t.Errorf("expected error naming /missing.bin, got: %v", err)
+		}
+		if _, ok := results["/missing.bin"]; ok {
+			t.Error("expected no entry for the missing file")
+		}
+		if len(results[paths[1]]) != 1 {
+			t.Errorf("expected %s to be downloaded, got %v", paths[1], results[paths[1]])
+		}
+	})
+
+	t.Run("DownloadManyBytes returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).DownloadManyBytes(paths)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_DisableConcurrentReads verifies downloads send one read
+// at a time with DisableConcurrentReads and still produce the file
+func TestConnection_DisableConcurrentReads(t *testing.T) {
+	payload := make([]byte, 1<<20)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+
+	download := func(t *testing.T, disabled bool) int {
+		t.Helper()
+		server := NewMockServer(t)
+		server.ReadLatency = time.Millisecond
+		server.WriteFile(t, "/large.bin", payload)
+		opts := server.Options()
+		opts.DisableConcurrentReads = disabled
+		conn, err := (&Client{}).ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer conn.Close()
+
+		localPath := filepath.Join(t.TempDir(), "large.bin")
+		if err := conn.Download("/large.bin", localPath); err != nil {
+			t.Fatalf("Download failed: %v", err)
+		}
+		got, err := os.ReadFile(localPath)
+		if err != nil {
+			t.Fatalf("read downloaded file: %v", err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Error("downloaded content differs from the remote file")
+		}
+		return server.MaxConcurrentReads()
+	}
+
+	t.Run("Disabled reads one request at a time", func(t *testing.T) {
+		if peak := download(t, true); peak != 1 {
+			t.Errorf("expected 1 read in flight at most, got %d", peak)
+		}
+	})
+
+	t.Run("Default pipelines reads", func(t *testing.T) {
+		if peak := download(t, false); peak < 2 {
+			t.Errorf("expected concurrent reads by default, got at most %d", peak)
+		}
+	})
+}
+
+// TestConnection_DisableConcurrentWrites verifies uploads send one write
+// at a time with DisableConcurrentWrites, even on a write session
+func TestConnection_DisableConcurrentWrites(t *testing.T) {
+	payload := make([]byte, 1<<20)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+
+	upload := func(t *testing.T, disabled bool) int {
+		t.Helper()
+		server := NewMockServer(t)
+		server.WriteLatency = time.Millisecond
+		opts := server.Options()
+		opts.DisableConcurrentWrites = disabled
+		conn, err := (&Client{}).ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer conn.Close()
+		writer, err := conn.OpenWriteSession()
+		if err != nil {
+			t.Fatalf("OpenWriteSession failed: %v", err)
+		}
+		defer writer.Close()
+
+		if err := writer.Upload(payload, "/large.bin"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if !bytes.Equal(server.ReadFile(t, "/large.bin"), payload) {
+			t.Error("remote content differs from the upload")
+		}
+		return server.MaxConcurrentWrites()
+	}
+
+	t.Run("Disabled writes one request at a time", func(t *testing.T) {
+		if peak := upload(t, true); peak != 1 {
+			t.Errorf("expected 1 write in flight at most, got %d", peak)
+		}
+	})
+
+	t.Run("Write sessions write concurrently by default", func(t *testing.T) {
+		if peak := upload(t, false); peak < 2 {
+			t.Errorf("expected concurrent writes, got at most %d", peak)
+		}
+	})
+}
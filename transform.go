@@ -0,0 +1,68 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// UploadTransform applies transformer to srcbytes and uploads the result to
+// dstPath, e.g. to compress, encrypt or sign a payload on the way out
+// Nothing is uploaded if transformer returns an error
+func (c *Connection) UploadTransform(srcbytes []byte, dstPath string, transformer func([]byte) ([]byte, error)) (err error) {
+	var n int64
+	defer c.observeTransfer("uploadTransform", dstPath, "", time.Now(), &n, &err)
+
+	if c.client() == nil {
+		return errors.New("not connected")
+	}
+	if transformer == nil {
+		return errors.New("no transformer")
+	}
+
+	data, err := transformer(srcbytes)
+	if err != nil {
+		return fmt.Errorf("transform upload: %w", err)
+	}
+
+	// transformer may be a JavaScript function, so it runs on the calling
+	// goroutine rather than inside withTimeout
+	if err := withReplayErr(c, func(ctx context.Context) error { return c.upload(ctx, data, dstPath) }); err != nil {
+		return err
+	}
+	n = int64(len(data))
+	return nil
+}
+
+// DownloadTransform reads remotePath into memory, applies transformer and
+// writes the result to localPath, e.g. to decompress or decrypt a download
+// The local file is not written if transformer returns an error
+func (c *Connection) DownloadTransform(remotePath, localPath string, transformer func([]byte) ([]byte, error)) (err error) {
+	var n int64
+	defer c.observeTransfer("downloadTransform", remotePath, localPath, time.Now(), &n, &err)
+
+	if c.client() == nil {
+		return errors.New("not connected")
+	}
+	if transformer == nil {
+		return errors.New("no transformer")
+	}
+
+	data, err := withReplay(c, func(ctx context.Context) ([]byte, error) { return c.readAll(ctx, remotePath) })
+	if err != nil {
+		return err
+	}
+
+	data, err = transformer(data)
+	if err != nil {
+		return fmt.Errorf("transform download: %w", err)
+	}
+
+	if err := os.WriteFile(localPath, data, 0o644); err != nil {
+		return fmt.Errorf("write local file: %w", err)
+	}
+	n = int64(len(data))
+	return nil
+}
@@ -0,0 +1,108 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestConnection_UploadTransform verifies the transformed content is
+// uploaded and a transformer error aborts the upload
+func TestConnection_UploadTransform(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("Transformed content is uploaded", func(t *testing.T) {
+		err := conn.UploadTransform([]byte("payload"), "/upper.txt", func(data []byte) ([]byte, error) {
+			return bytes.ToUpper(data), nil
+		})
+		if err != nil {
+			t.Fatalf("UploadTransform failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/upper.txt")); got != "PAYLOAD" {
+			t.Errorf("expected 'PAYLOAD', got %q", got)
+		}
+	})
+
+	t.Run("Transformer error aborts the upload", func(t *testing.T) {
+		errSign := errors.New("signing key unavailable")
+		err := conn.UploadTransform([]byte("payload"), "/unsigned.txt", func([]byte) ([]byte, error) {
+			return nil, errSign
+		})
+		if !errors.Is(err, errSign) {
+			t.Errorf("expected transformer error, got: %v", err)
+		}
+		if _, err := conn.Stat("/unsigned.txt"); !errors.Is(err, ErrRemoteNotFound) {
+			t.Errorf("expected nothing uploaded, got: %v", err)
+		}
+	})
+
+	t.Run("UploadTransform returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).UploadTransform([]byte("data"), "/upper.txt", func(data []byte) ([]byte, error) {
+			return data, nil
+		})
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_DownloadTransform verifies the transformed content is
+// written locally and a transformer error leaves no local file
+func TestConnection_DownloadTransform(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/lower.txt", []byte("payload"))
+	dir := t.TempDir()
+
+	t.Run("Transformed content is written", func(t *testing.T) {
+		localPath := filepath.Join(dir, "upper.txt")
+		err := conn.DownloadTransform("/lower.txt", localPath, func(data []byte) ([]byte, error) {
+			return bytes.ToUpper(data), nil
+		})
+		if err != nil {
+			t.Fatalf("DownloadTransform failed: %v", err)
+		}
+		got, err := os.ReadFile(localPath)
+		if err != nil {
+			t.Fatalf("read local file: %v", err)
+		}
+		if string(got) != "PAYLOAD" {
+			t.Errorf("expected 'PAYLOAD', got %q", got)
+		}
+	})
+
+	t.Run("Transformer error leaves no local file", func(t *testing.T) {
+		localPath := filepath.Join(dir, "corrupt.txt")
+		errDecrypt := errors.New("bad ciphertext")
+		err := conn.DownloadTransform("/lower.txt", localPath, func([]byte) ([]byte, error) {
+			return nil, errDecrypt
+		})
+		if !errors.Is(err, errDecrypt) {
+			t.Errorf("expected transformer error, got: %v", err)
+		}
+		if _, err := os.Stat(localPath); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected no local file, got: %v", err)
+		}
+	})
+
+	t.Run("Missing remote file returns error", func(t *testing.T) {
+		err := conn.DownloadTransform("/missing.txt", filepath.Join(dir, "missing.txt"), func(data []byte) ([]byte, error) {
+			return data, nil
+		})
+		if err == nil {
+			t.Error("expected error for missing remote file")
+		}
+	})
+
+	t.Run("DownloadTransform returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).DownloadTransform("/lower.txt", filepath.Join(dir, "x.txt"), func(data []byte) ([]byte, error) {
+			return data, nil
+		})
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrTooManyFiles is returned by LsRecursive when a tree has more entries
+// than ConnectionOptions.MaxLsFiles
+var ErrTooManyFiles = errors.New("too many files")
+
+// LsRecursive lists every file and directory below remotePath, depth first
+// Each entry has the Ls properties plus path, the full remote path. Fails
+// with ErrTooManyFiles once more than MaxLsFiles entries have been found,
+// and with ErrPathDenied if the path policy denies any of them
+func (c *Connection) LsRecursive(remotePath string) (_ []map[string]interface{}, err error) {
+	defer c.observe("lsRecursive", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) ([]map[string]interface{}, error) { return c.lsRecursive(ctx, remotePath) })
+}
+
+func (c *Connection) lsRecursive(ctx context.Context, remotePath string) ([]map[string]interface{}, error) {
+	if c.client() == nil {
+		return nil, errors.New("not connected")
+	}
+	if err := c.checkPathPolicy(remotePath); err != nil {
+		return nil, err
+	}
+	remotePath = c.resolvePath(remotePath)
+
+	limit := c.opts.MaxLsFiles
+	if limit <= 0 {
+		limit = defaultMaxLsFiles
+	}
+
+	results := []map[string]interface{}{}
+	walker := c.client().Walk(remotePath)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := walker.Err(); err != nil {
+			return nil, fmt.Errorf("walk %s: %w", walker.Path(), err)
+		}
+		if walker.Path() == remotePath {
+			continue
+		}
+		if err := c.checkPathPolicy(walker.Path()); err != nil {
+			return nil, err
+		}
+
+		if len(results) == limit {
+			return nil, fmt.Errorf("%w: stopped after %d entries below %s (maxLsFiles %d)",
+				ErrTooManyFiles, len(results), remotePath, limit)
+		}
+
+		entry := fileInfoMap(walker.Stat())
+		entry["path"] = walker.Path()
+		results = append(results, entry)
+	}
+
+	return results, nil
+}
+
+// LsChunked lists remotePath in the background and sends the entries on
+// the returned data channel in chunks of at most chunkSize, with the Ls
+// properties, so a huge directory can be processed incrementally
+// pkg/sftp exposes no paged directory read, so the listing is still fetched
+// in one go; what is saved is building the result maps for every entry up
+// front. Both channels are closed once all chunks are sent; a failure is
+// sent on the error channel before they close. The data channel must be
+// drained, otherwise the listing goroutine blocks until the VU context is
+// done and then sends operation aborted. OperationTimeout bounds the
+// directory read
+func (c *Connection) LsChunked(remotePath string, chunkSize int) (<-chan []map[string]interface{}, <-chan error) {
+	data := make(chan []map[string]interface{})
+	errs := make(chan error, 1)
+	start := time.Now()
+
+	fail := func(err error) (<-chan []map[string]interface{}, <-chan error) {
+		c.observe("lsChunked", remotePath, start, &err)
+		errs <- err
+		close(data)
+		close(errs)
+		return data, errs
+	}
+
+	if c.client() == nil {
+		return fail(errors.New("not connected"))
+	}
+	if chunkSize <= 0 {
+		return fail(fmt.Errorf("invalid chunk size %d", chunkSize))
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(data)
+
+		err := func() error {
+			entries, err := withReplay(c, func(ctx context.Context) ([]os.FileInfo, error) { return c.readDir(ctx, remotePath) })
+			if err != nil {
+				return err
+			}
+
+			for len(entries) > 0 {
+				n := min(chunkSize, len(entries))
+				select {
+				case data <- chunkInfoMaps(entries[:n]):
+				case <-c.vuContext().Done():
+					return fmt.Errorf("operation aborted: %w", c.vuContext().Err())
+				}
+				entries = entries[n:]
+			}
+			return nil
+		}()
+		c.observe("lsChunked", remotePath, start, &err)
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return data, errs
+}
+
+// LsAsync lists remotePath in the background and sends each entry on the
+// returned data channel, with the Ls properties
+// Like LsChunked, the listing is fetched in one go, as pkg/sftp has no
+// paged directory read; entries are converted and sent one at a time. Both
+// channels are closed once all entries are sent; a failure is sent on the
+// error channel before they close. The data channel must be drained,
+// otherwise the listing goroutine blocks until the VU context is done and
+// then sends operation aborted. OperationTimeout bounds the directory read,
+// which is cancelled when the operation is
+func (c *Connection) LsAsync(remotePath string) (<-chan map[string]interface{}, <-chan error) {
+	data := make(chan map[string]interface{})
+	errs := make(chan error, 1)
+	start := time.Now()
+
+	finish := func(err error) {
+		c.observe("lsAsync", remotePath, start, &err)
+		if err != nil {
+			errs <- err
+		}
+	}
+
+	if c.client() == nil {
+		finish(errors.New("not connected"))
+		close(data)
+		close(errs)
+		return data, errs
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(data)
+
+		finish(func() error {
+			entries, err := withReplay(c, func(ctx context.Context) ([]os.FileInfo, error) { return c.readDir(ctx, remotePath) })
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range entries {
+				select {
+				case data <- fileInfoMap(entry):
+				case <-c.vuContext().Done():
+					return fmt.Errorf("operation aborted: %w", c.vuContext().Err())
+				}
+			}
+			return nil
+		}())
+	}()
+
+	return data, errs
+}
+
+// readDir lists remotePath for LsChunked and LsAsync, subject to the path
+// policy, giving up once ctx is done
+func (c *Connection) readDir(ctx context.Context, remotePath string) ([]os.FileInfo, error) {
+	if err := c.checkPathPolicy(remotePath); err != nil {
+		return nil, err
+	}
+	entries, err := c.client().ReadDirContext(ctx, c.resolvePath(remotePath))
+	if err != nil {
+		return nil, fmt.Errorf("read directory: %w", err)
+	}
+	return entries, nil
+}
+
+// chunkInfoMaps converts a chunk of directory entries to Ls result maps
+func chunkInfoMaps(entries []os.FileInfo) []map[string]interface{} {
+	chunk := make([]map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		chunk[i] = fileInfoMap(entry)
+	}
+	return chunk
+}
@@ -0,0 +1,268 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+// TestConnection_LsRecursive verifies nested entries are listed and the
+// MaxLsFiles cap is enforced
+func TestConnection_LsRecursive(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	if err := conn.client().MkdirAll("/tree/sub"); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		server.WriteFile(t, fmt.Sprintf("/tree/sub/%d.txt", i), []byte("x"))
+	}
+
+	t.Run("Nested entries are listed", func(t *testing.T) {
+		entries, err := conn.LsRecursive("/tree")
+		if err != nil {
+			t.Fatalf("LsRecursive failed: %v", err)
+		}
+		if len(entries) != 6 {
+			t.Fatalf("expected sub and 5 files, got %d entries", len(entries))
+		}
+		if entries[0]["path"] != "/tree/sub" || entries[0]["isDir"] != true {
+			t.Errorf("expected /tree/sub first, got %v", entries[0])
+		}
+		if entries[1]["path"] != "/tree/sub/0.txt" || entries[1]["name"] != "0.txt" {
+			t.Errorf("expected /tree/sub/0.txt second, got %v", entries[1])
+		}
+	})
+
+	t.Run("Cap is enforced with the partial count", func(t *testing.T) {
+		opts := server.Options()
+		opts.MaxLsFiles = 3
+		capped, err := (&Client{}).ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer capped.Close()
+
+		_, err = capped.LsRecursive("/tree")
+		if !errors.Is(err, ErrTooManyFiles) {
+			t.Fatalf("expected ErrTooManyFiles, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "after 3 entries") {
+			t.Errorf("expected the partial count in %q", err)
+		}
+	})
+
+	t.Run("LsRecursive returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).LsRecursive("/tree")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_LsChunked verifies a directory is listed in chunks of at
+// most the chunk size
+func TestConnection_LsChunked(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	for i := 0; i < 25; i++ {
+		server.WriteFile(t, fmt.Sprintf("/big/%02d.txt", i), []byte("x"))
+	}
+
+	t.Run("Entries arrive in chunks", func(t *testing.T) {
+		data, errs := conn.LsChunked("/big", 10)
+		var sizes []int
+		names := map[interface{}]bool{}
+		for chunk := range data {
+			sizes = append(sizes, len(chunk))
+			for _, entry := range chunk {
+				names[entry["name"]] = true
+			}
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("LsChunked failed: %v", err)
+		}
+		if fmt.Sprint(sizes) != "[10 10 5]" {
+			t.Errorf("expected chunks of 10, 10 and 5, got %v", sizes)
+		}
+		if len(names) != 25 || !names["00.txt"] {
+			t.Errorf("expected 25 distinct entries, got %d", len(names))
+		}
+	})
+
+	t.Run("Missing directory sends error", func(t *testing.T) {
+		data, errs := conn.LsChunked("/missing", 10)
+		for range data {
+			t.Error("expected no chunks")
+		}
+		if err := <-errs; err == nil {
+			t.Error("expected error for a missing directory")
+		}
+	})
+
+	t.Run("Invalid chunk size sends error", func(t *testing.T) {
+		_, errs := conn.LsChunked("/big", 0)
+		if err := <-errs; err == nil {
+			t.Error("expected error for chunk size 0")
+		}
+	})
+
+	t.Run("Ended VU context sends error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		vuConn, err := (&Client{vu: &modulestest.VU{CtxField: ctx}}).ConnectWithOptions(server.Options())
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer vuConn.Close()
+
+		data, errs := vuConn.LsChunked("/big", 10)
+		<-data
+		// Let the listing goroutine block on sending the next chunk, then
+		// see the context end before draining
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		time.Sleep(50 * time.Millisecond)
+		for range data {
+		}
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got: %v", err)
+		}
+	})
+
+	t.Run("LsChunked sends error when not connected", func(t *testing.T) {
+		_, errs := (&Connection{}).LsChunked("/big", 10)
+		if err := <-errs; err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_LsChunked_NoGoroutineLeak verifies the listing goroutines
+// of LsChunked and LsAsync exit once the results are drained
+func TestConnection_LsChunked_NoGoroutineLeak(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	for i := 0; i < 10; i++ {
+		server.WriteFile(t, fmt.Sprintf("/big/%d.txt", i), []byte("x"))
+	}
+
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		data, errs := conn.LsChunked("/big", 3)
+		for range data {
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("LsChunked failed: %v", err)
+		}
+		_, errs = conn.LsChunked("/missing", 3)
+		<-errs
+
+		entries, errs := conn.LsAsync("/big")
+		for range entries {
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("LsAsync failed: %v", err)
+		}
+	}
+
+	waitForGoroutines(t, baseline)
+}
+
+// TestConnection_LsAsync verifies directory entries are sent one by one
+func TestConnection_LsAsync(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	for i := 0; i < 25; i++ {
+		server.WriteFile(t, fmt.Sprintf("/big/%02d.txt", i), []byte("x"))
+	}
+
+	t.Run("Entries arrive individually", func(t *testing.T) {
+		data, errs := conn.LsAsync("/big")
+		names := map[interface{}]bool{}
+		for entry := range data {
+			if entry["size"] != int64(1) || entry["isDir"] != false {
+				t.Errorf("unexpected entry %v", entry)
+			}
+			names[entry["name"]] = true
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("LsAsync failed: %v", err)
+		}
+		if len(names) != 25 || !names["24.txt"] {
+			t.Errorf("expected 25 distinct entries, got %d", len(names))
+		}
+	})
+
+	t.Run("Missing directory sends error", func(t *testing.T) {
+		data, errs := conn.LsAsync("/missing")
+		for range data {
+			t.Error("expected no entries")
+		}
+		if err := <-errs; err == nil {
+			t.Error("expected error for a missing directory")
+		}
+	})
+
+	t.Run("Ended VU context sends error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		vuConn, err := (&Client{vu: &modulestest.VU{CtxField: ctx}}).ConnectWithOptions(server.Options())
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer vuConn.Close()
+
+		data, errs := vuConn.LsAsync("/big")
+		<-data
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		time.Sleep(50 * time.Millisecond)
+		for range data {
+		}
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got: %v", err)
+		}
+	})
+
+	t.Run("Ended VU context fails a pending directory read", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		vuConn, err := (&Client{vu: &modulestest.VU{CtxField: ctx}}).ConnectWithOptions(server.Options())
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer vuConn.Close()
+		server.SetLatency(300*time.Millisecond, 0)
+		defer server.SetLatency(0, 0)
+
+		start := time.Now()
+		data, errs := vuConn.LsAsync("/big")
+		time.AfterFunc(50*time.Millisecond, cancel)
+		for range data {
+		}
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+			t.Errorf("expected the read to give up on cancellation, took %v", elapsed)
+		}
+	})
+
+	t.Run("LsAsync sends error when not connected", func(t *testing.T) {
+		data, errs := (&Connection{}).LsAsync("/big")
+		if err := <-errs; err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+		if _, ok := <-data; ok {
+			t.Error("expected data channel to be closed")
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// CSVHeaderError is returned by ValidateCSV when the header row of a remote
+// CSV file differs from the expected headers
+type CSVHeaderError struct {
+	Path     string
+	Expected []string
+	Actual   []string
+}
+
+func (e *CSVHeaderError) Error() string {
+	for i := 0; i < max(len(e.Expected), len(e.Actual)); i++ {
+		switch {
+		case i >= len(e.Actual):
+			return fmt.Sprintf("csv header mismatch in %s: missing column %d %q", e.Path, i+1, e.Expected[i])
+		case i >= len(e.Expected):
+			return fmt.Sprintf("csv header mismatch in %s: unexpected column %d %q", e.Path, i+1, e.Actual[i])
+		case e.Actual[i] != e.Expected[i]:
+			return fmt.Sprintf("csv header mismatch in %s: column %d is %q, want %q", e.Path, i+1, e.Actual[i], e.Expected[i])
+		}
+	}
+	return fmt.Sprintf("csv header mismatch in %s", e.Path)
+}
+
+// ValidateCSV streams a remote CSV file, checks that its header row equals
+// expectedHeaders (same order and case) and counts the data rows after it
+// Returns a *CSVHeaderError on a header mismatch
+func (c *Connection) ValidateCSV(remotePath string, expectedHeaders []string) (rowCount int64, err error) {
+	defer c.observe("validateCSV", remotePath, time.Now(), &err)
+	return withReplay(c, func(ctx context.Context) (int64, error) { return c.validateCSV(ctx, remotePath, expectedHeaders) })
+}
+
+func (c *Connection) validateCSV(ctx context.Context, remotePath string, expectedHeaders []string) (rowCount int64, err error) {
+	if c.client() == nil {
+		return 0, errors.New("not connected")
+	}
+
+	file, err := c.open(ctx, remotePath)
+	if err != nil {
+		return 0, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("csv file %s is empty", remotePath)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read csv header: %w", err)
+	}
+	if !slices.Equal(header, expectedHeaders) {
+		return 0, &CSVHeaderError{
+			Path:     remotePath,
+			Expected: expectedHeaders,
+			Actual:   append([]string(nil), header...),
+		}
+	}
+
+	for {
+		_, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rowCount, nil
+		}
+		if err != nil {
+			return rowCount, fmt.Errorf("read csv row %d: %w", rowCount+1, err)
+		}
+		rowCount++
+	}
+}
+
+// JSONSchemaError is returned by ValidateJSON when a remote JSON document
+// does not satisfy the schema; Violations lists every failed constraint
+type JSONSchemaError struct {
+	Path       string
+	Violations []string
+}
+
+func (e *JSONSchemaError) Error() string {
+	return fmt.Sprintf("json schema validation failed for %s: %s", e.Path, strings.Join(e.Violations, "; "))
+}
+
+// ValidateJSON downloads a remote JSON file and validates it against the
+// JSON schema given as a string (draft 2020-12 unless the schema's $schema
+// says otherwise)
+// Returns a *JSONSchemaError listing all violations if validation fails
+func (c *Connection) ValidateJSON(remotePath, schema string) (err error) {
+	defer c.observe("validateJSON", remotePath, time.Now(), &err)
+
+	schemaDoc, err := jsonschema.UnmarshalJSON(strings.NewReader(schema))
+	if err != nil {
+		return fmt.Errorf("parse json schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", schemaDoc); err != nil {
+		return fmt.Errorf("invalid json schema: %w", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		return fmt.Errorf("invalid json schema: %w", err)
+	}
+
+	data, err := withReplay(c, func(ctx context.Context) ([]byte, error) { return c.readAll(ctx, remotePath) })
+	if err != nil {
+		return err
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("parse json file %s: %w", remotePath, err)
+	}
+
+	err = compiled.Validate(doc)
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		schemaErr := &JSONSchemaError{Path: remotePath}
+		for _, unit := range validationErr.BasicOutput().Errors {
+			if unit.Error == nil {
+				continue
+			}
+			schemaErr.Violations = append(schemaErr.Violations,
+				fmt.Sprintf("at '%s': %s", unit.InstanceLocation, unit.Error))
+		}
+		return schemaErr
+	}
+	if err != nil {
+		return fmt.Errorf("validate json: %w", err)
+	}
+
+	return nil
+}
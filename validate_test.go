@@ -0,0 +1,132 @@
+package sftp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestConnection_ValidateCSV verifies header validation and data row counting
+func TestConnection_ValidateCSV(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	headers := []string{"id", "name", "amount"}
+
+	t.Run("Matching header counts data rows", func(t *testing.T) {
+		server.WriteFile(t, "/out.csv", []byte("id,name,amount\n1,alice,10\n2,\"bob, jr\",20\n"))
+		rows, err := conn.ValidateCSV("/out.csv", headers)
+		if err != nil {
+			t.Fatalf("ValidateCSV failed: %v", err)
+		}
+		if rows != 2 {
+			t.Errorf("expected 2 rows, got %d", rows)
+		}
+	})
+
+	t.Run("Header only has zero rows", func(t *testing.T) {
+		server.WriteFile(t, "/empty-rows.csv", []byte("id,name,amount\n"))
+		rows, err := conn.ValidateCSV("/empty-rows.csv", headers)
+		if err != nil || rows != 0 {
+			t.Errorf("expected 0 rows and no error, got %d and %v", rows, err)
+		}
+	})
+
+	mismatches := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{"Wrong case", "id,Name,amount\n", `column 2 is "Name", want "name"`},
+		{"Wrong order", "name,id,amount\n", `column 1 is "name", want "id"`},
+		{"Missing column", "id,name\n", `missing column 3 "amount"`},
+		{"Extra column", "id,name,amount,note\n", `unexpected column 4 "note"`},
+	}
+	for _, tt := range mismatches {
+		t.Run(tt.name+" returns CSVHeaderError", func(t *testing.T) {
+			server.WriteFile(t, "/bad.csv", []byte(tt.content))
+			_, err := conn.ValidateCSV("/bad.csv", headers)
+
+			var headerErr *CSVHeaderError
+			if !errors.As(err, &headerErr) {
+				t.Fatalf("expected *CSVHeaderError, got: %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error containing %q, got: %v", tt.message, err)
+			}
+		})
+	}
+
+	t.Run("Malformed row returns error", func(t *testing.T) {
+		server.WriteFile(t, "/ragged.csv", []byte("id,name,amount\n1,alice\n"))
+		if _, err := conn.ValidateCSV("/ragged.csv", headers); err == nil {
+			t.Error("expected error for row with missing fields, got nil")
+		}
+	})
+
+	t.Run("Empty file returns error", func(t *testing.T) {
+		server.WriteFile(t, "/empty.csv", nil)
+		if _, err := conn.ValidateCSV("/empty.csv", headers); err == nil {
+			t.Error("expected error for empty file, got nil")
+		}
+	})
+}
+
+// TestConnection_ValidateJSON verifies remote JSON is checked against a schema
+// and every violation is reported
+func TestConnection_ValidateJSON(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	schema := `{
+		"type": "object",
+		"required": ["id", "name"],
+		"properties": {
+			"id": {"type": "integer"},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		}
+	}`
+
+	t.Run("Valid document passes", func(t *testing.T) {
+		server.WriteFile(t, "/ok.json", []byte(`{"id": 1, "name": "a", "tags": ["x"]}`))
+		if err := conn.ValidateJSON("/ok.json", schema); err != nil {
+			t.Errorf("expected valid document, got: %v", err)
+		}
+	})
+
+	t.Run("Violations are all listed", func(t *testing.T) {
+		server.WriteFile(t, "/bad.json", []byte(`{"id": "one", "tags": ["x", 2]}`))
+		err := conn.ValidateJSON("/bad.json", schema)
+
+		var schemaErr *JSONSchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Fatalf("expected *JSONSchemaError, got: %v", err)
+		}
+		for _, want := range []string{"missing property 'name'", "at '/id'", "at '/tags/1'"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected violation %q in: %v", want, err)
+			}
+		}
+	})
+
+	t.Run("Malformed JSON returns error", func(t *testing.T) {
+		server.WriteFile(t, "/broken.json", []byte(`{"id":`))
+		err := conn.ValidateJSON("/broken.json", schema)
+		var schemaErr *JSONSchemaError
+		if err == nil || errors.As(err, &schemaErr) {
+			t.Errorf("expected parse error, got: %v", err)
+		}
+	})
+
+	t.Run("Invalid schema returns error", func(t *testing.T) {
+		if err := conn.ValidateJSON("/ok.json", `{"type": 12}`); err == nil {
+			t.Error("expected error for invalid schema, got nil")
+		}
+	})
+
+	t.Run("ValidateJSON returns error when not connected", func(t *testing.T) {
+		err := (&Connection{}).ValidateJSON("/ok.json", schema)
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package sftp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/sftp"
+)
+
+// connectWebSocket runs the SFTP protocol directly over a WebSocket, as
+// offered by some SFTP-as-a-service providers; there is no SSH layer
+// Username and Password, if set, are sent as HTTP basic auth on the upgrade
+func (c *Client) connectWebSocket(ctx context.Context, opts ConnectionOptions) (*Connection, error) {
+	header := http.Header{}
+	if opts.Username != "" || opts.Password != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(opts.Username + ":" + opts.Password))
+		header.Set("Authorization", "Basic "+credentials)
+	}
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 10 * time.Second,
+	}
+	ws, _, err := dialer.DialContext(ctx, opts.WebSocketURL, header)
+	if err != nil {
+		return nil, fmt.Errorf("websocket dial failed: %w", err)
+	}
+
+	rw := newWebSocketConn(ws)
+	sftpClient, err := sftp.NewClientPipe(rw, rw)
+	if err != nil {
+		rw.Close()
+		return nil, fmt.Errorf("sftp client creation failed: %w", err)
+	}
+
+	conn := c.newConnection(opts, nil, sftpClient)
+	conn.notifyConnect()
+	return conn, nil
+}
+
+// webSocketConn adapts a WebSocket to the byte stream pkg/sftp expects,
+// sending each write as one binary message and reading messages back to back
+type webSocketConn struct {
+	ws *websocket.Conn
+
+	readMu sync.Mutex
+	reader io.Reader
+
+	writeMu sync.Mutex
+}
+
+func newWebSocketConn(ws *websocket.Conn) *webSocketConn {
+	return &webSocketConn{ws: ws}
+}
+
+func (w *webSocketConn) Read(p []byte) (int, error) {
+	w.readMu.Lock()
+	defer w.readMu.Unlock()
+
+	for {
+		if w.reader == nil {
+			_, r, err := w.ws.NextReader()
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return 0, io.EOF
+			}
+			if err != nil {
+				return 0, err
+			}
+			w.reader = r
+		}
+
+		n, err := w.reader.Read(p)
+		if err == io.EOF {
+			w.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (w *webSocketConn) Write(p []byte) (int, error) {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+
+	if err := w.ws.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *webSocketConn) Close() error {
+	w.writeMu.Lock()
+	deadline := time.Now().Add(time.Second)
+	_ = w.ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+	w.writeMu.Unlock()
+	return w.ws.Close()
+}
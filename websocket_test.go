@@ -0,0 +1,74 @@
+package sftp
+
+import (
+	"testing"
+	"time"
+)
+
+// TestClient_Connect_WebSocket verifies SFTP runs over a WebSocket transport
+func TestClient_Connect_WebSocket(t *testing.T) {
+	server := NewMockServer(t)
+	wsURL := server.ServeWebSocket(t)
+	c := &Client{}
+
+	t.Run("Operations work over WebSocket", func(t *testing.T) {
+		conn, err := c.Connect("", server.User, server.Password, 0, WithWebSocketTransport(wsURL))
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer conn.Close()
+
+		data := make([]byte, 256*1024)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		if err := conn.Upload(data, "/ws.bin"); err != nil {
+			t.Fatalf("upload failed: %v", err)
+		}
+		if got := server.ReadFile(t, "/ws.bin"); string(got) != string(data) {
+			t.Errorf("remote content mismatch: got %d bytes, want %d", len(got), len(data))
+		}
+
+		result, err := conn.Ls("/")
+		if err != nil {
+			t.Fatalf("ls failed: %v", err)
+		}
+		if len(result.Entries) != 1 || result.Entries[0]["name"] != "ws.bin" {
+			t.Errorf("unexpected entries: %v", result.Entries)
+		}
+	})
+
+	t.Run("Close calls OnDisconnect", func(t *testing.T) {
+		disconnected := make(chan error, 1)
+		conn, err := c.Connect("", server.User, server.Password, 0, WithWebSocketTransport(wsURL),
+			WithOnDisconnect(func(_ *Connection, err error) { disconnected <- err }))
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+
+		closeErr := conn.Close()
+		select {
+		case err := <-disconnected:
+			if err != closeErr {
+				t.Errorf("expected the Close error %v, got %v", closeErr, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected OnDisconnect to be called")
+		}
+
+		conn.Close()
+		select {
+		case <-disconnected:
+			t.Error("expected OnDisconnect to be called once")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Wrong credentials fail the upgrade", func(t *testing.T) {
+		conn, err := c.Connect("", server.User, "wrong", 0, WithWebSocketTransport(wsURL))
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected websocket dial error, got nil")
+		}
+	})
+}
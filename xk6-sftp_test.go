@@ -1,19 +1,40 @@
 package sftp
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"math/big"
+	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/js/modulestest"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/net/dns/dnsmessage"
 )
 
 // TestConnection_NotConnected verifies that all Connection methods
 // return appropriate errors when the connection is not established
 func TestConnection_NotConnected(t *testing.T) {
 	conn := &Connection{
-		sshClient:  nil,
-		sftpClient: nil,
+		sshClient: nil,
 	}
 
 	t.Run("Upload returns error when not connected", func(t *testing.T) {
@@ -54,8 +75,7 @@ func TestConnection_NotConnected(t *testing.T) {
 func TestConnection_Close(t *testing.T) {
 	t.Run("Close on nil connection succeeds", func(t *testing.T) {
 		conn := &Connection{
-			sshClient:  nil,
-			sftpClient: nil,
+			sshClient: nil,
 		}
 		err := conn.Close()
 		if err != nil {
@@ -65,14 +85,13 @@ func TestConnection_Close(t *testing.T) {
 
 	t.Run("Close sets clients to nil", func(t *testing.T) {
 		conn := &Connection{
-			sshClient:  nil,
-			sftpClient: nil,
+			sshClient: nil,
 		}
 		_ = conn.Close()
 		if conn.sshClient != nil {
 			t.Error("expected sshClient to be nil after Close")
 		}
-		if conn.sftpClient != nil {
+		if conn.client() != nil {
 			t.Error("expected sftpClient to be nil after Close")
 		}
 	})
@@ -94,6 +113,17 @@ func TestClient_Connect_InvalidHost(t *testing.T) {
 		if conn != nil {
 			t.Error("expected nil connection on error")
 		}
+
+		var connErr *ConnectionError
+		if !errors.As(err, &connErr) {
+			t.Fatalf("expected *ConnectionError, got %T: %v", err, err)
+		}
+		if connErr.Host != "127.0.0.1" || connErr.Port != 65534 || connErr.Username != "user" {
+			t.Errorf("unexpected error fields %+v", connErr)
+		}
+		if !strings.HasPrefix(err.Error(), "failed to connect to user@127.0.0.1:65534: tcp dial failed: ") {
+			t.Errorf("unexpected error message %q", err)
+		}
 	})
 
 	t.Run("Connect to unreachable host returns error", func(t *testing.T) {
@@ -114,6 +144,244 @@ func TestClient_Connect_InvalidHost(t *testing.T) {
 	})
 }
 
+// TestClient_ConnectContext verifies cancelling the context aborts the dial
+// and the SSH handshake
+func TestClient_ConnectContext(t *testing.T) {
+	c := &Client{}
+
+	t.Run("Cancelled context fails before dialing", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := c.ConnectContext(ctx, "127.0.0.1", "user", "pass", 65534)
+		var connErr *ConnectionError
+		if !errors.As(err, &connErr) || !errors.Is(err, context.Canceled) {
+			t.Errorf("expected ConnectionError wrapping context.Canceled, got: %v", err)
+		}
+	})
+
+	t.Run("Deadline aborts a stalled handshake", func(t *testing.T) {
+		// Accepts TCP connections but never speaks SSH
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		defer listener.Close()
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			<-done
+			conn.Close()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		port := listener.Addr().(*net.TCPAddr).Port
+
+		start := time.Now()
+		_, err = c.ConnectContext(ctx, "127.0.0.1", "user", "pass", port)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got: %v", err)
+		}
+		var connErr *ConnectionError
+		if !errors.As(err, &connErr) || connErr.Port != port {
+			t.Errorf("expected ConnectionError for port %d, got: %v", port, err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected the handshake to be aborted quickly, took %v", elapsed)
+		}
+	})
+}
+
+// TestClient_Connect_PhaseTimeouts verifies each phase of connecting is
+// bounded by its own timeout and reported by name
+func TestClient_Connect_PhaseTimeouts(t *testing.T) {
+	c := &Client{}
+
+	t.Run("Stalled SSH handshake exceeds sshAuthTimeout", func(t *testing.T) {
+		// Accepts TCP connections but never speaks SSH
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		defer listener.Close()
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			<-done
+			conn.Close()
+		}()
+
+		port := listener.Addr().(*net.TCPAddr).Port
+		_, err = c.Connect("127.0.0.1", "user", "pass", port, WithPhaseTimeouts(0, 100*time.Millisecond, 0))
+		if !errors.Is(err, ErrConnectTimeout) || !strings.Contains(err.Error(), "sshAuthTimeout") {
+			t.Errorf("expected ErrConnectTimeout naming sshAuthTimeout, got: %v", err)
+		}
+		var connErr *ConnectionError
+		if !errors.As(err, &connErr) {
+			t.Errorf("expected ConnectionError, got: %v", err)
+		}
+	})
+
+	t.Run("Stalled SFTP session exceeds sftpInitTimeout", func(t *testing.T) {
+		server := NewMockServer(t)
+		server.StallSFTP = true
+
+		opts := server.Options()
+		opts.SFTPInitTimeout = 100 * time.Millisecond
+		_, err := c.ConnectWithOptions(opts)
+		if !errors.Is(err, ErrConnectTimeout) || !strings.Contains(err.Error(), "sftpInitTimeout") {
+			t.Errorf("expected ErrConnectTimeout naming sftpInitTimeout, got: %v", err)
+		}
+	})
+
+	t.Run("Handshake deadline is lifted once connected", func(t *testing.T) {
+		server := NewMockServer(t)
+		opts := server.Options()
+		opts.SSHAuthTimeout = 100 * time.Millisecond
+		conn, err := c.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer conn.Close()
+
+		time.Sleep(200 * time.Millisecond)
+		if _, err := conn.Ls("/"); err != nil {
+			t.Errorf("expected the connection to outlive sshAuthTimeout, got: %v", err)
+		}
+	})
+}
+
+// TestClient_Connect_DNS verifies WithDNS resolves the host through the
+// given DNS server
+func TestClient_Connect_DNS(t *testing.T) {
+	server := NewMockServer(t)
+	dnsAddr, queries := serveDNS(t, "sftp.test.", net.IPv4(127, 0, 0, 1))
+	c := &Client{}
+
+	t.Run("Host is resolved by the custom server", func(t *testing.T) {
+		conn, err := c.Connect("sftp.test", server.User, server.Password, server.Port, WithDNS(dnsAddr))
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		conn.Close()
+		if queries.Load() == 0 {
+			t.Error("expected the custom DNS server to be queried")
+		}
+	})
+
+	t.Run("Unknown host fails to resolve", func(t *testing.T) {
+		conn, err := c.Connect("other.test", server.User, server.Password, server.Port, WithDNS(dnsAddr))
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected resolution error, got nil")
+		}
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) {
+			t.Errorf("expected *net.DNSError, got: %v", err)
+		}
+	})
+}
+
+// serveDNS answers A queries for name with ip on a local UDP port, and
+// every other query with NXDOMAIN. Returns the address and a query counter
+func serveDNS(t *testing.T, name string, ip net.IP) (string, *atomic.Int32) {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen for DNS: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	var queries atomic.Int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			queries.Add(1)
+
+			q := query.Questions[0]
+			reply := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+				Questions: query.Questions,
+			}
+			switch {
+			case !strings.EqualFold(q.Name.String(), name):
+				reply.RCode = dnsmessage.RCodeNameError
+			case q.Type == dnsmessage.TypeA:
+				reply.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte(ip.To4())},
+				}}
+			}
+			packed, err := reply.Pack()
+			if err != nil {
+				continue
+			}
+			pc.WriteTo(packed, addr)
+		}
+	}()
+
+	return pc.LocalAddr().String(), &queries
+}
+
+// TestClient_Connect_Pipe verifies UsePipe runs the server command instead
+// of requesting the sftp subsystem
+func TestClient_Connect_Pipe(t *testing.T) {
+	server := NewMockServer(t)
+	server.ServerCommand = "/usr/libexec/sftp-server -l INFO"
+	c := &Client{}
+
+	t.Run("Command speaks SFTP", func(t *testing.T) {
+		conn, err := c.Connect(server.Host, server.User, server.Password, server.Port,
+			WithPipe("/usr/libexec/sftp-server -l INFO"))
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		defer conn.Close()
+
+		if err := conn.Upload([]byte("piped"), "/piped.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/piped.txt")); got != "piped" {
+			t.Errorf("expected 'piped', got %q", got)
+		}
+	})
+
+	t.Run("Unknown command fails", func(t *testing.T) {
+		conn, err := c.Connect(server.Host, server.User, server.Password, server.Port,
+			WithPipe("/bin/false"))
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected error for a command the server rejects, got nil")
+		}
+	})
+
+	t.Run("Missing command returns error", func(t *testing.T) {
+		opts := server.Options()
+		opts.UsePipe = true
+		if _, err := c.ConnectWithOptions(opts); err == nil || !strings.Contains(err.Error(), "sftpServerCommand") {
+			t.Errorf("expected missing command error, got: %v", err)
+		}
+	})
+}
+
 // TestModule_NewModuleInstance verifies module instantiation
 func TestModule_NewModuleInstance(t *testing.T) {
 	m := &Module{}
@@ -135,22 +403,98 @@ func TestModule_NewModuleInstance(t *testing.T) {
 	})
 }
 
+// TestClient_VUPropagation verifies that the VU handed to NewModuleInstance
+// reaches the connections created by the Client
+func TestClient_VUPropagation(t *testing.T) {
+	m := &Module{}
+	mockVU := &modulestest.VU{}
+
+	c, ok := m.NewModuleInstance(mockVU).(*Client)
+	if !ok {
+		t.Fatal("expected instance to be *Client")
+	}
+
+	t.Run("Client keeps the VU", func(t *testing.T) {
+		if c.vu != modules.VU(mockVU) {
+			t.Error("expected Client to hold the VU passed to NewModuleInstance")
+		}
+	})
+
+	t.Run("Connection inherits the VU", func(t *testing.T) {
+		conn := c.newConnection(ConnectionOptions{}, nil, nil)
+		if conn.vu == nil {
+			t.Fatal("expected non-nil VU on connection")
+		}
+		if conn.vu != modules.VU(mockVU) {
+			t.Error("expected connection VU to match the Client VU")
+		}
+	})
+
+	t.Run("vuState is nil without a VU", func(t *testing.T) {
+		conn := (&Client{}).newConnection(ConnectionOptions{}, nil, nil)
+		if conn.vuState() != nil {
+			t.Error("expected nil state for connection without a VU")
+		}
+	})
+}
+
 // TestClient_Exports verifies the exported functions
 func TestClient_Exports(t *testing.T) {
 	c := &Client{}
 	exports := c.Exports()
 
-	t.Run("Exports contains connect function", func(t *testing.T) {
+	// expectedExports is the public JavaScript API surface. Adding a named
+	// export without listing it here fails this test on purpose.
+	expectedExports := []string{
+		"connect",
+		"connectWithOptions",
+		"connectFromEnv",
+		"connectURI",
+		"connectGSSAPI",
+		"namedConnect",
+		"namedGet",
+		"namedClose",
+		"createPool",
+		"poolReady",
+		"createMuxedTransport",
+		"exportPrometheus",
+		"uploadFanOut",
+		"downloadConsensus",
+		"roundRobinPool",
+		"newRouter",
+		"newPipeline",
+	}
+
+	t.Run("Exports contains all expected functions", func(t *testing.T) {
 		if exports.Named == nil {
 			t.Fatal("expected Named exports, got nil")
 		}
 
-		connectFn, exists := exports.Named["connect"]
-		if !exists {
-			t.Error("expected 'connect' in Named exports")
+		for _, name := range expectedExports {
+			fn, exists := exports.Named[name]
+			if !exists {
+				t.Errorf("expected '%s' in Named exports", name)
+				continue
+			}
+			if fn == nil {
+				t.Errorf("expected non-nil '%s' function", name)
+				continue
+			}
+			if reflect.TypeOf(fn).Kind() != reflect.Func {
+				t.Errorf("expected '%s' to be callable, got %T", name, fn)
+			}
 		}
-		if connectFn == nil {
-			t.Error("expected non-nil connect function")
+	})
+
+	t.Run("Exports contains no untested functions", func(t *testing.T) {
+		expected := make(map[string]bool, len(expectedExports))
+		for _, name := range expectedExports {
+			expected[name] = true
+		}
+		for name := range exports.Named {
+			if !expected[name] {
+				t.Errorf("named export '%s' is not covered by this test", name)
+			}
 		}
 	})
 
@@ -226,8 +570,7 @@ func TestConcurrency_ConnectionMethods(t *testing.T) {
 
 			// Each goroutine creates its own connection struct
 			conn := &Connection{
-				sshClient:  nil,
-				sftpClient: nil,
+				sshClient: nil,
 			}
 
 			for j := 0; j < iterations; j++ {
@@ -279,11 +622,11 @@ func TestConnection_Integration(t *testing.T) {
 			t.Errorf("Ls failed: %v", err)
 		}
 		if files == nil {
-			t.Error("expected files, got nil")
+			t.Fatal("expected files, got nil")
 		}
 
 		// Verify file info structure
-		for _, f := range files {
+		for _, f := range files.Entries {
 			if _, ok := f["name"]; !ok {
 				t.Error("file info missing 'name' field")
 			}
@@ -318,3 +661,718 @@ func TestConnection_Integration(t *testing.T) {
 		}
 	})
 }
+
+// TestClient_Connect_HostKeyAlgorithms verifies host key algorithm
+// restrictions are negotiated with the server
+func TestClient_Connect_HostKeyAlgorithms(t *testing.T) {
+	server := NewMockServer(t) // ed25519 host key only
+	c := &Client{}
+
+	t.Run("Matching algorithm connects", func(t *testing.T) {
+		opts := server.Options()
+		opts.HostKeyAlgorithms = []string{ssh.KeyAlgoED25519}
+
+		conn, err := c.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("Unsupported algorithm returns clear error", func(t *testing.T) {
+		opts := server.Options()
+		opts.HostKeyAlgorithms = []string{ssh.KeyAlgoRSASHA256}
+
+		conn, err := c.ConnectWithOptions(opts)
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected error for unsupported host key algorithm, got nil")
+		}
+		if !strings.Contains(err.Error(), "none of the requested host key algorithms") {
+			t.Errorf("expected host key algorithm error, got: %v", err)
+		}
+		var negErr *ssh.AlgorithmNegotiationError
+		if !errors.As(err, &negErr) {
+			t.Errorf("expected wrapped *ssh.AlgorithmNegotiationError, got: %v", err)
+		}
+	})
+}
+
+// TestClient_Connect_PreferredHostKeyAlgorithms verifies preferred host key
+// algorithms are negotiated first without excluding the others
+func TestClient_Connect_PreferredHostKeyAlgorithms(t *testing.T) {
+	// A 1024-bit RSA key with MinRSAKeyBits 2048 fails the handshake, which
+	// shows whether the RSA or the Ed25519 host key was negotiated
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	rsaSigner, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("create signer: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	edSigner, err := ssh.NewSignerFromKey(edKey)
+	if err != nil {
+		t.Fatalf("create signer: %v", err)
+	}
+	c := &Client{}
+
+	t.Run("RSA is negotiated first by default", func(t *testing.T) {
+		opts := NewMockServer(t, rsaSigner, edSigner).Options()
+		opts.MinRSAKeyBits = 2048
+		conn, err := c.ConnectWithOptions(opts)
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected the RSA host key to be negotiated, got a connection")
+		}
+		if !errors.Is(err, ErrWeakHostKey) {
+			t.Errorf("expected ErrWeakHostKey, got: %v", err)
+		}
+	})
+
+	t.Run("Preferred algorithm is negotiated first", func(t *testing.T) {
+		opts := NewMockServer(t, rsaSigner, edSigner).Options()
+		opts.MinRSAKeyBits = 2048
+		opts.PreferredHostKeyAlgorithms = []string{ssh.KeyAlgoED25519}
+		conn, err := c.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("expected the Ed25519 host key to be negotiated, got: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("Falls back when the server lacks the preferred type", func(t *testing.T) {
+		opts := NewMockServer(t, rsaSigner).Options()
+		opts.PreferredHostKeyAlgorithms = []string{ssh.KeyAlgoED25519}
+		conn, err := c.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("expected fallback to RSA, got: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("HostKeyAlgorithms still restricts the list", func(t *testing.T) {
+		opts := ConnectionOptions{
+			HostKeyAlgorithms:          []string{ssh.KeyAlgoRSASHA256, ssh.KeyAlgoED25519},
+			PreferredHostKeyAlgorithms: []string{ssh.KeyAlgoECDSA256, ssh.KeyAlgoED25519},
+		}
+		want := []string{ssh.KeyAlgoED25519, ssh.KeyAlgoRSASHA256}
+		if got := opts.hostKeyAlgorithms(); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+}
+
+// TestClient_Connect_MinRSAKeyBits verifies short RSA host keys are rejected
+// only when a minimum size is set
+func TestClient_Connect_MinRSAKeyBits(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("create signer: %v", err)
+	}
+	server := NewMockServer(t, signer)
+	c := &Client{}
+
+	t.Run("Any size is accepted by default", func(t *testing.T) {
+		conn, err := c.ConnectWithOptions(server.Options())
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("Key at the minimum connects", func(t *testing.T) {
+		opts := server.Options()
+		opts.MinRSAKeyBits = 1024
+		conn, err := c.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("Shorter key returns ErrWeakHostKey", func(t *testing.T) {
+		opts := server.Options()
+		opts.MinRSAKeyBits = 2048
+		conn, err := c.ConnectWithOptions(opts)
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected error for a 1024-bit host key, got nil")
+		}
+		if !errors.Is(err, ErrWeakHostKey) {
+			t.Errorf("expected ErrWeakHostKey, got: %v", err)
+		}
+	})
+}
+
+// TestClient_Connect_KnownHostsFile verifies host keys are checked against
+// known_hosts under the [host]:port form for the mock server's random port
+func TestClient_Connect_KnownHostsFile(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("create signer: %v", err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	otherSigner, err := ssh.NewSignerFromKey(otherKey)
+	if err != nil {
+		t.Fatalf("create signer: %v", err)
+	}
+	server := NewMockServer(t, signer)
+	c := &Client{}
+
+	connect := func(t *testing.T, lines ...string) error {
+		t.Helper()
+		file := filepath.Join(t.TempDir(), "known_hosts")
+		if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+			t.Fatalf("write known_hosts: %v", err)
+		}
+		opts := server.Options()
+		opts.KnownHostsFile = file
+		conn, err := c.ConnectWithOptions(opts)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+	bracketed := fmt.Sprintf("[%s]:%d", server.Host, server.Port)
+
+	t.Run("Key listed under [host]:port connects", func(t *testing.T) {
+		if err := connect(t, knownhosts.Line([]string{bracketed}, signer.PublicKey())); err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+	})
+
+	t.Run("Hashed entry connects", func(t *testing.T) {
+		line := knownhosts.Line([]string{knownhosts.HashHostname(bracketed)}, signer.PublicKey())
+		if err := connect(t, line); err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+	})
+
+	t.Run("Bare host entry only covers port 22", func(t *testing.T) {
+		err := connect(t, knownhosts.Line([]string{server.Host}, signer.PublicKey()))
+		if !errors.Is(err, ErrUntrustedHostKey) {
+			t.Fatalf("expected ErrUntrustedHostKey, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), bracketed+" is not listed") {
+			t.Errorf("expected the [host]:port lookup key in the error, got: %v", err)
+		}
+	})
+
+	t.Run("Different key returns ErrUntrustedHostKey", func(t *testing.T) {
+		err := connect(t, knownhosts.Line([]string{bracketed}, otherSigner.PublicKey()))
+		if !errors.Is(err, ErrUntrustedHostKey) || !strings.Contains(err.Error(), "does not match") {
+			t.Errorf("expected mismatch error, got: %v", err)
+		}
+	})
+
+	t.Run("Missing file returns error", func(t *testing.T) {
+		opts := server.Options()
+		opts.KnownHostsFile = filepath.Join(t.TempDir(), "missing")
+		conn, err := c.ConnectWithOptions(opts)
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected error for a missing known_hosts file")
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected os.ErrNotExist, got: %v", err)
+		}
+	})
+}
+
+// TestClient_Connect_PrivateKey verifies public key authentication via Option
+func TestClient_Connect_PrivateKey(t *testing.T) {
+	server := NewMockServer(t)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("create signer: %v", err)
+	}
+	server.AuthorizedKey = signer.PublicKey()
+
+	c := &Client{}
+
+	t.Run("Authorized key connects without password", func(t *testing.T) {
+		conn, err := c.Connect(server.Host, server.User, "", server.Port,
+			WithPrivateKey(string(pem.EncodeToMemory(block))))
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("Unknown key is rejected", func(t *testing.T) {
+		_, other, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+		block, err := ssh.MarshalPrivateKey(other, "")
+		if err != nil {
+			t.Fatalf("marshal key: %v", err)
+		}
+
+		conn, err := c.Connect(server.Host, server.User, "", server.Port,
+			WithPrivateKey(string(pem.EncodeToMemory(block))))
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected authentication error, got nil")
+		}
+	})
+}
+
+// TestClient_Connect_AuthMethods verifies custom auth methods replace the defaults
+func TestClient_Connect_AuthMethods(t *testing.T) {
+	server := NewMockServer(t)
+	c := &Client{}
+
+	t.Run("Custom methods are used instead of the password", func(t *testing.T) {
+		opts := server.Options()
+		opts.Password = "wrong"
+		opts.PrivateKey = "ignored"
+		opts.AuthMethods = []ssh.AuthMethod{ssh.Password(server.Password)}
+
+		conn, err := c.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("Failing custom methods are not followed by the defaults", func(t *testing.T) {
+		opts := server.Options()
+		opts.AuthMethods = []ssh.AuthMethod{ssh.Password("wrong")}
+
+		conn, err := c.ConnectWithOptions(opts)
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected authentication error, got nil")
+		}
+	})
+}
+
+// TestClient_Connect_TLSClientCert verifies SSH is tunnelled through TLS
+// with the configured client certificate and the gateway is verified
+func TestClient_Connect_TLSClientCert(t *testing.T) {
+	serverCertPEM, serverKeyPEM := selfSignedCert(t)
+	serverCert, err := tls.X509KeyPair(serverCertPEM, serverKeyPEM)
+	if err != nil {
+		t.Fatalf("load server key pair: %v", err)
+	}
+	clientCert, clientKey := selfSignedCert(t)
+
+	var presented atomic.Bool
+	server := NewMockServer(t)
+	server.SetTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			presented.Store(len(rawCerts) > 0)
+			return nil
+		},
+	})
+
+	c := &Client{}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, serverCertPEM, 0o600); err != nil {
+		t.Fatalf("write CA file: %v", err)
+	}
+	tlsOptions := func(opts ...Option) ConnectionOptions {
+		o := server.Options()
+		o.TLSClientCert = string(clientCert)
+		o.TLSClientKey = string(clientKey)
+		for _, opt := range opts {
+			opt(&o)
+		}
+		return o
+	}
+
+	t.Run("Valid certificate connects", func(t *testing.T) {
+		opts := tlsOptions(WithTLSVerification(caFile, ""))
+
+		conn, err := c.ConnectWithOptions(opts)
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		defer conn.Close()
+
+		if !presented.Load() {
+			t.Error("expected client certificate to be presented")
+		}
+		if _, err := conn.Ls("/"); err != nil {
+			t.Errorf("ls over TLS failed: %v", err)
+		}
+	})
+
+	t.Run("Unverified gateway is rejected", func(t *testing.T) {
+		conn, err := c.ConnectWithOptions(tlsOptions())
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected a self-signed gateway certificate to be rejected")
+		}
+		var unknownAuthority x509.UnknownAuthorityError
+		if !errors.As(err, &unknownAuthority) {
+			t.Errorf("expected x509.UnknownAuthorityError, got: %v", err)
+		}
+	})
+
+	t.Run("Server name is verified", func(t *testing.T) {
+		conn, err := c.ConnectWithOptions(tlsOptions(WithTLSVerification(caFile, "gateway.test")))
+		if err != nil {
+			t.Fatalf("expected connection for a name in the certificate, got: %v", err)
+		}
+		conn.Close()
+
+		conn, err = c.ConnectWithOptions(tlsOptions(WithTLSVerification(caFile, "other.test")))
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected a name not in the certificate to be rejected")
+		}
+		var invalidHost x509.HostnameError
+		if !errors.As(err, &invalidHost) {
+			t.Errorf("expected x509.HostnameError, got: %v", err)
+		}
+	})
+
+	t.Run("TLSInsecureSkipVerify accepts any certificate", func(t *testing.T) {
+		conn, err := c.ConnectWithOptions(tlsOptions(WithTLSInsecureSkipVerify(true)))
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("Unreadable CA file returns error", func(t *testing.T) {
+		if _, err := c.ConnectWithOptions(tlsOptions(WithTLSVerification(filepath.Join(t.TempDir(), "missing.pem"), ""))); err == nil {
+			t.Error("expected error for a missing CA file")
+		}
+		if _, err := c.ConnectWithOptions(tlsOptions(WithTLSVerification(os.DevNull, ""))); err == nil {
+			t.Error("expected error for a CA file without certificates")
+		}
+	})
+
+	t.Run("Invalid certificate returns ErrInvalidTLSCert", func(t *testing.T) {
+		_, err := c.Connect(server.Host, server.User, server.Password, server.Port,
+			WithTLSClientCert("not a cert", "not a key"))
+		if !errors.Is(err, ErrInvalidTLSCert) {
+			t.Errorf("expected ErrInvalidTLSCert, got: %v", err)
+		}
+	})
+}
+
+// selfSignedCert generates a PEM encoded self-signed certificate and key
+func selfSignedCert(t *testing.T) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "xk6-sftp test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		// Valid for the mock server and its own issuer, so it can be
+		// trusted through TLSCAFile
+		DNSNames:              []string{"gateway.test"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM
+}
+
+// TestClient_Connect_RekeyThreshold verifies transfers survive frequent rekeying
+func TestClient_Connect_RekeyThreshold(t *testing.T) {
+	server := NewMockServer(t)
+
+	opts := server.Options()
+	opts.RekeyThreshold = 4096
+
+	conn, err := (&Client{}).ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("expected connection, got error: %v", err)
+	}
+	defer conn.Close()
+
+	data := strings.Repeat("rekey", 64*1024)
+	if err := conn.Upload([]byte(data), "/rekey.txt"); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if got := string(server.ReadFile(t, "/rekey.txt")); got != data {
+		t.Errorf("expected %d bytes, got %d", len(data), len(got))
+	}
+}
+
+// TestClient_Connect_BannerVerifier verifies the server banner can abort a connection
+func TestClient_Connect_BannerVerifier(t *testing.T) {
+	server := NewMockServer(t)
+	server.Banner = "Authorized use only\n"
+
+	c := &Client{}
+	requireBanner := func(banner string) error {
+		if !strings.Contains(banner, "Authorized use only") {
+			return fmt.Errorf("unexpected banner %q", banner)
+		}
+		return nil
+	}
+
+	t.Run("Accepted banner connects", func(t *testing.T) {
+		conn, err := c.Connect(server.Host, server.User, server.Password, server.Port,
+			WithBannerVerifier(requireBanner))
+		if err != nil {
+			t.Fatalf("expected connection, got error: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("Rejected banner aborts", func(t *testing.T) {
+		conn, err := c.Connect(server.Host, server.User, server.Password, server.Port,
+			WithBannerVerifier(func(string) error { return errors.New("wrong banner") }))
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected banner verification error, got nil")
+		}
+		if !strings.Contains(err.Error(), "banner verification failed") {
+			t.Errorf("expected banner verification error, got: %v", err)
+		}
+		var connErr *ConnectionError
+		if !errors.As(err, &connErr) || connErr.Host != server.Host || connErr.Port != server.Port {
+			t.Errorf("expected *ConnectionError for %s:%d, got: %v", server.Host, server.Port, err)
+		}
+	})
+
+	t.Run("Missing banner is verified as empty", func(t *testing.T) {
+		silent := NewMockServer(t)
+
+		conn, err := c.Connect(silent.Host, silent.User, silent.Password, silent.Port,
+			WithBannerVerifier(requireBanner))
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected banner verification error, got nil")
+		}
+	})
+}
+
+// TestConnection_MockServer verifies the core operations against the
+// in-process mock server
+func TestConnection_MockServer(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	t.Run("Upload writes the remote file", func(t *testing.T) {
+		if err := conn.Upload([]byte("mock content"), "/mock.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/mock.txt")); got != "mock content" {
+			t.Errorf("remote content mismatch: got %q", got)
+		}
+	})
+
+	t.Run("Ls lists the remote file", func(t *testing.T) {
+		files, err := conn.Ls("/")
+		if err != nil {
+			t.Fatalf("Ls failed: %v", err)
+		}
+		if len(files.Entries) != 1 || files.Entries[0]["name"] != "mock.txt" {
+			t.Errorf("expected only mock.txt, got %v", files.Entries)
+		}
+	})
+
+	t.Run("Download copies the remote file", func(t *testing.T) {
+		localPath := filepath.Join(t.TempDir(), "mock.txt")
+		if err := conn.Download("/mock.txt", localPath); err != nil {
+			t.Fatalf("Download failed: %v", err)
+		}
+		data, err := os.ReadFile(localPath)
+		if err != nil {
+			t.Fatalf("Failed to read downloaded file: %v", err)
+		}
+		if string(data) != "mock content" {
+			t.Errorf("Downloaded content mismatch: got %q", string(data))
+		}
+	})
+
+	t.Run("Upload overwrites an existing file", func(t *testing.T) {
+		// Regression test: the file must be opened write-only and
+		// truncated, not appended to
+		for i := 0; i < 2; i++ {
+			if err := conn.Upload([]byte("hello"), "/twice.txt"); err != nil {
+				t.Fatalf("Upload %d failed: %v", i, err)
+			}
+		}
+		if got := string(server.ReadFile(t, "/twice.txt")); got != "hello" {
+			t.Errorf("expected %q after uploading twice, got %q", "hello", got)
+		}
+
+		if err := conn.Upload([]byte("hi"), "/twice.txt"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if got := string(server.ReadFile(t, "/twice.txt")); got != "hi" {
+			t.Errorf("expected a shorter upload to truncate the file, got %q", got)
+		}
+	})
+}
+
+// TestConnection_MockServer_MaxFiles verifies uploads beyond the server's
+// file quota fail with SSH_FX_FAILURE
+func TestConnection_MockServer_MaxFiles(t *testing.T) {
+	const maxFiles = 3
+	server := NewMockServer(t)
+	server.SetMaxFiles(maxFiles)
+	conn := server.Connect(t)
+
+	for i := 0; i < maxFiles; i++ {
+		if err := conn.Upload([]byte("data"), fmt.Sprintf("/file-%d.txt", i)); err != nil {
+			t.Fatalf("upload %d within the quota failed: %v", i, err)
+		}
+	}
+
+	t.Run("Upload beyond the quota fails", func(t *testing.T) {
+		err := conn.Upload([]byte("data"), fmt.Sprintf("/file-%d.txt", maxFiles))
+		var statusErr *sftp.StatusError
+		if !errors.As(err, &statusErr) || statusErr.FxCode() != sftp.ErrSSHFxFailure {
+			t.Errorf("expected SSH_FX_FAILURE, got: %v", err)
+		}
+	})
+
+	t.Run("Existing files can still be overwritten", func(t *testing.T) {
+		if err := conn.Upload([]byte("updated"), "/file-0.txt"); err != nil {
+			t.Errorf("expected overwrite to succeed, got: %v", err)
+		}
+	})
+}
+
+// TestConnection_Ls verifies listings are capped at MaxLsFiles and report
+// the directory's total, and that LsLegacy returns every entry
+func TestConnection_Ls(t *testing.T) {
+	server := NewMockServer(t)
+	for i := 0; i < 5; i++ {
+		server.WriteFile(t, fmt.Sprintf("/dir/file-%d.txt", i), []byte("data"))
+	}
+
+	opts := server.Options()
+	opts.MaxLsFiles = 3
+	conn, err := (&Client{}).ConnectWithOptions(opts)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	t.Run("Listing beyond MaxLsFiles is truncated", func(t *testing.T) {
+		result, err := conn.Ls("/dir")
+		if err != nil {
+			t.Fatalf("Ls failed: %v", err)
+		}
+		if len(result.Entries) != 3 || result.Total != 5 || !result.Truncated {
+			t.Errorf("expected 3 of 5 entries truncated, got %d of %d (truncated=%v)",
+				len(result.Entries), result.Total, result.Truncated)
+		}
+	})
+
+	t.Run("Listing within MaxLsFiles is complete", func(t *testing.T) {
+		result, err := server.Connect(t).Ls("/dir")
+		if err != nil {
+			t.Fatalf("Ls failed: %v", err)
+		}
+		if len(result.Entries) != 5 || result.Total != 5 || result.Truncated {
+			t.Errorf("expected all 5 entries, got %d of %d (truncated=%v)",
+				len(result.Entries), result.Total, result.Truncated)
+		}
+	})
+
+	t.Run("LsLegacy returns every entry", func(t *testing.T) {
+		entries, err := conn.LsLegacy("/dir")
+		if err != nil {
+			t.Fatalf("LsLegacy failed: %v", err)
+		}
+		if len(entries) != 5 || entries[0]["name"] != "file-0.txt" {
+			t.Errorf("expected 5 entries, got %v", entries)
+		}
+	})
+
+	t.Run("LsLegacy returns error when not connected", func(t *testing.T) {
+		_, err := (&Connection{}).LsLegacy("/dir")
+		if err == nil || err.Error() != "not connected" {
+			t.Errorf("expected 'not connected' error, got: %v", err)
+		}
+	})
+}
+
+// TestNoFileHandleLeak_Download verifies every Download closes its remote
+// handle, including downloads that fail after opening it
+func TestNoFileHandleLeak_Download(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+	server.WriteFile(t, "/source.bin", []byte("payload"))
+	localPath := filepath.Join(t.TempDir(), "dest.bin")
+
+	for i := 0; i < 1000; i++ {
+		if err := conn.Download("/source.bin", localPath); err != nil {
+			t.Fatalf("download %d failed: %v", i, err)
+		}
+		if n := server.OpenHandles(); n != 0 {
+			t.Fatalf("download %d left %d handles open", i, n)
+		}
+	}
+
+	t.Run("Failed local create closes the handle", func(t *testing.T) {
+		err := conn.Download("/source.bin", filepath.Join(t.TempDir(), "missing", "dest.bin"))
+		if err == nil {
+			t.Fatal("expected error for a missing local directory")
+		}
+		if n := server.OpenHandles(); n != 0 {
+			t.Errorf("expected no open handles, got %d", n)
+		}
+	})
+}
+
+// TestNoFileHandleLeak_Upload verifies every Upload closes its remote handle
+func TestNoFileHandleLeak_Upload(t *testing.T) {
+	server := NewMockServer(t)
+	conn := server.Connect(t)
+
+	for i := 0; i < 1000; i++ {
+		if err := conn.Upload([]byte("payload"), "/dest.bin"); err != nil {
+			t.Fatalf("upload %d failed: %v", i, err)
+		}
+		if n := server.OpenHandles(); n != 0 {
+			t.Fatalf("upload %d left %d handles open", i, n)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlValue captures one value of a YAML mapping so it can be decoded
+// once the field it belongs to is known
+type yamlValue struct {
+	unmarshal func(interface{}) error
+}
+
+func (v *yamlValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	v.unmarshal = unmarshal
+	return nil
+}
+
+// UnmarshalYAML decodes ConnectionOptions from a YAML mapping whose keys are
+// the JavaScript option names, e.g. host, privateKey or operationTimeout
+// Durations accept strings such as "30s". Go-only fields such as hooks
+// cannot be set, and unknown keys are rejected so typos do not go unnoticed
+func (opts *ConnectionOptions) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw map[string]*yamlValue
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	fields := yamlFields()
+	var errs []error
+	for key, value := range raw {
+		index, ok := fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown option %q", key))
+			continue
+		}
+		field := reflect.ValueOf(opts).Elem().Field(index)
+		if err := value.unmarshal(field.Addr().Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("option %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// yamlFields maps the js name of every ConnectionOptions field YAML can set
+// to its index; functions and js:"-" fields are left out
+func yamlFields() map[string]int {
+	t := reflect.TypeOf(ConnectionOptions{})
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("js"), ",")
+		if name == "" || name == "-" || f.Type.Kind() == reflect.Func {
+			continue
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+// LoadConnectionOptionsYAML reads ConnectionOptions from a YAML document,
+// see ConnectionOptions.UnmarshalYAML
+func LoadConnectionOptionsYAML(r io.Reader) (*ConnectionOptions, error) {
+	var opts ConnectionOptions
+	if err := yaml.NewDecoder(r).Decode(&opts); err != nil {
+		return nil, fmt.Errorf("decode yaml options: %w", err)
+	}
+	return &opts, nil
+}
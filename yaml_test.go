@@ -0,0 +1,77 @@
+package sftp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadConnectionOptionsYAML verifies options are read by their
+// JavaScript names and unknown or Go-only keys are rejected
+func TestLoadConnectionOptionsYAML(t *testing.T) {
+	t.Run("Options are decoded by js name", func(t *testing.T) {
+		doc := `
+host: sftp.example.com
+port: 2222
+username: loadtest
+password: secret
+label: worker
+operationTimeout: 30s
+sshAuthTimeout: 1m
+hostKeyAlgorithms: [ssh-ed25519]
+denyPaths:
+  - /etc/*
+progressMetrics: true
+`
+		opts, err := LoadConnectionOptionsYAML(strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("LoadConnectionOptionsYAML failed: %v", err)
+		}
+
+		want := ConnectionOptions{
+			Host:              "sftp.example.com",
+			Port:              2222,
+			Username:          "loadtest",
+			Password:          "secret",
+			Label:             "worker",
+			OperationTimeout:  30 * time.Second,
+			SSHAuthTimeout:    time.Minute,
+			HostKeyAlgorithms: []string{"ssh-ed25519"},
+			DenyPaths:         []string{"/etc/*"},
+			ProgressMetrics:   true,
+		}
+		if !reflect.DeepEqual(*opts, want) {
+			t.Errorf("expected %+v, got %+v", want, *opts)
+		}
+	})
+
+	t.Run("Unknown keys are rejected", func(t *testing.T) {
+		_, err := LoadConnectionOptionsYAML(strings.NewReader("host: a\nhots: b\n"))
+		if err == nil || !strings.Contains(err.Error(), `unknown option "hots"`) {
+			t.Errorf("expected unknown option error, got: %v", err)
+		}
+	})
+
+	t.Run("Go-only fields cannot be set", func(t *testing.T) {
+		for _, key := range []string{"onConnect", "bannerVerifier", "AuthMethods"} {
+			_, err := LoadConnectionOptionsYAML(strings.NewReader(key + ": x\n"))
+			if err == nil {
+				t.Errorf("expected %s to be rejected", key)
+			}
+		}
+	})
+
+	t.Run("Wrong type names the option", func(t *testing.T) {
+		_, err := LoadConnectionOptionsYAML(strings.NewReader("port: twenty-two\n"))
+		if err == nil || !strings.Contains(err.Error(), "option port") {
+			t.Errorf("expected error naming port, got: %v", err)
+		}
+	})
+
+	t.Run("Malformed YAML returns error", func(t *testing.T) {
+		if _, err := LoadConnectionOptionsYAML(strings.NewReader("host: [unclosed\n")); err == nil {
+			t.Error("expected error for malformed YAML")
+		}
+	})
+}